@@ -36,6 +36,34 @@ func main() {
 		}
 	}()
 
+	// start webhooks
+	go func() {
+		if err := app.WebhookConsumer.Start(rootCtx); err != nil {
+			app.Logger.Error("webhook consumer error", zap.Error(err))
+		}
+	}()
+	go func() {
+		if err := app.WebhookDispatcher.Start(rootCtx); err != nil {
+			app.Logger.Error("webhook dispatcher error", zap.Error(err))
+		}
+	}()
+
+	// start export jobs
+	go func() {
+		if err := app.ExportWorker.Start(rootCtx); err != nil {
+			app.Logger.Error("export worker error", zap.Error(err))
+		}
+	}()
+
+	// start link health monitor
+	if app.HealthMonitor != nil {
+		go func() {
+			if err := app.HealthMonitor.Start(rootCtx); err != nil {
+				app.Logger.Error("health monitor error", zap.Error(err))
+			}
+		}()
+	}
+
 	// wait stop signal
 	c := make(chan os.Signal, 2)
 	signal.Notify(c, syscall.SIGINT, syscall.SIGTERM)
@@ -0,0 +1,75 @@
+// Command apikey issues API keys for the shorter API. A new key gets its own
+// workspace, or joins an existing one when -workspace is given:
+//
+//	go run cmd/apikey/main.go -name "marketing"
+//	go run cmd/apikey/main.go -name "analyst" -workspace 1 -role viewer
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"shorter/internal/auth"
+	"shorter/internal/config"
+	"shorter/internal/model"
+	"shorter/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func main() {
+	name := flag.String("name", "", "human readable name of the key owner")
+	workspaceID := flag.Int64("workspace", 0, "existing workspace to join instead of creating a new one")
+	role := flag.String("role", string(model.RoleEditor), "role in the existing workspace: admin, editor or viewer")
+	flag.Parse()
+
+	if *name == "" {
+		log.Fatal("-name is required")
+	}
+	if !model.Role(*role).IsValid() {
+		log.Fatalf("unknown role %q", *role)
+	}
+
+	cfg, err := config.LoadConfig(".")
+	if err != nil {
+		log.Fatalf("cannot load config: %v", err)
+	}
+
+	ctx := context.Background()
+	db, err := pgxpool.New(ctx, cfg.DB.URL)
+	if err != nil {
+		log.Fatalf("failed to connect to DB: %v", err)
+	}
+	defer db.Close()
+
+	plain, err := auth.GenerateKey()
+	if err != nil {
+		log.Fatalf("failed to generate key: %v", err)
+	}
+
+	key := &model.APIKey{
+		Name:   *name,
+		Prefix: auth.KeyPrefix(plain),
+	}
+	if err := repository.NewAPIKeyRepository(db).Create(ctx, key, auth.HashKey(plain)); err != nil {
+		log.Fatalf("failed to save key: %v", err)
+	}
+
+	workspaces := repository.NewWorkspaceRepository(db)
+	if *workspaceID != 0 {
+		if err := workspaces.SetMember(ctx, *workspaceID, key.ID, model.Role(*role)); err != nil {
+			log.Fatalf("failed to join workspace: %v", err)
+		}
+	} else {
+		ws := &model.Workspace{Name: *name}
+		if err := workspaces.Create(ctx, ws, key.ID); err != nil {
+			log.Fatalf("failed to create workspace: %v", err)
+		}
+		*workspaceID = ws.ID
+	}
+
+	fmt.Printf("key id: %d\n", key.ID)
+	fmt.Printf("workspace id: %d\n", *workspaceID)
+	fmt.Printf("api key (shown only once): %s\n", plain)
+}
@@ -0,0 +1,68 @@
+package alias
+
+import (
+	"context"
+	"fmt"
+)
+
+const base62 = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+const (
+	StrategyRandom   = "random"
+	StrategySequence = "sequence"
+	StrategyHashids  = "hashids"
+	StrategyWords    = "words"
+)
+
+// Generator produces candidate aliases for new links. Candidates are not
+// guaranteed to be free, the caller retries on the unique constraint.
+type Generator interface {
+	Generate(ctx context.Context) (string, error)
+	// Grow makes future aliases longer, called when collisions show that
+	// the current keyspace is filling up.
+	Grow()
+}
+
+// Sequence returns the next value of a monotonic counter.
+type Sequence func(ctx context.Context) (int64, error)
+
+// New builds the generator for the configured strategy.
+func New(strategy string, length int, salt string, seq Sequence) (Generator, error) {
+	if length <= 0 {
+		length = 6
+	}
+
+	switch strategy {
+	case "", StrategyRandom:
+		return NewRandom(length), nil
+	case StrategySequence:
+		return NewSequential(seq, length), nil
+	case StrategyHashids:
+		return NewHashids(seq, salt, length), nil
+	case StrategyWords:
+		return NewWords(), nil
+	default:
+		return nil, fmt.Errorf("unknown alias strategy %q", strategy)
+	}
+}
+
+// encode writes n in the given alphabet, left padded with the zero digit
+// up to width characters.
+func encode(n int64, alphabet string, width int) string {
+	base := int64(len(alphabet))
+
+	var buf []byte
+	for n > 0 {
+		buf = append(buf, alphabet[n%base])
+		n /= base
+	}
+	for len(buf) < width {
+		buf = append(buf, alphabet[0])
+	}
+
+	for i, j := 0, len(buf)-1; i < j; i, j = i+1, j-1 {
+		buf[i], buf[j] = buf[j], buf[i]
+	}
+
+	return string(buf)
+}
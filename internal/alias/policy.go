@@ -0,0 +1,154 @@
+package alias
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+//go:embed denylist.txt
+var defaultDenyList string
+
+// Rejection explains why an alias can not be used.
+type Rejection struct {
+	Rule   string
+	Reason string
+}
+
+func (r *Rejection) Error() string {
+	return r.Reason
+}
+
+// AsRejection unwraps a policy rejection from err.
+func AsRejection(err error) (*Rejection, bool) {
+	var r *Rejection
+	ok := errors.As(err, &r)
+
+	return r, ok
+}
+
+// Policy decides which aliases may be issued: names that shadow routes,
+// offensive words and protected brands are refused.
+type Policy struct {
+	reserved  map[string]struct{}
+	denyWords []string
+	brands    []string
+}
+
+func NewPolicy(reserved, denyWords, brands []string) *Policy {
+	p := &Policy{reserved: make(map[string]struct{})}
+	p.Reserve(reserved...)
+
+	for _, w := range append(parseWordList(defaultDenyList), denyWords...) {
+		if w = normalizeWord(w); w != "" {
+			p.denyWords = append(p.denyWords, w)
+		}
+	}
+	for _, b := range brands {
+		if b = normalizeWord(b); b != "" {
+			p.brands = append(p.brands, b)
+		}
+	}
+
+	return p
+}
+
+// Reserve adds names that must never become aliases.
+func (p *Policy) Reserve(names ...string) {
+	for _, name := range names {
+		if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+			p.reserved[name] = struct{}{}
+		}
+	}
+}
+
+// ReserveRoutes reserves the first static segment of every registered route,
+// so /api or /metrics can not be shadowed by a link.
+func (p *Policy) ReserveRoutes(routes chi.Routes) error {
+	return chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		segment, _, _ := strings.Cut(strings.TrimPrefix(route, "/"), "/")
+		if segment != "" && !strings.HasPrefix(segment, "{") {
+			p.Reserve(segment)
+		}
+		return nil
+	})
+}
+
+// Check returns a *Rejection when the alias is not allowed.
+func (p *Policy) Check(alias string) error {
+	if _, ok := p.reserved[strings.ToLower(alias)]; ok {
+		return &Rejection{Rule: "reserved", Reason: "alias is reserved"}
+	}
+
+	normalized := normalizeWord(alias)
+	for _, b := range p.brands {
+		if strings.Contains(normalized, b) {
+			return &Rejection{Rule: "brand", Reason: "alias contains a protected brand name"}
+		}
+	}
+	for _, w := range p.denyWords {
+		if strings.Contains(normalized, w) {
+			return &Rejection{Rule: "profanity", Reason: "alias contains a forbidden word"}
+		}
+	}
+
+	return nil
+}
+
+// LoadWordList reads one word per line, # starts a comment.
+func LoadWordList(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseWordList(string(data)), nil
+}
+
+func parseWordList(data string) []string {
+	var words []string
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			words = append(words, line)
+		}
+	}
+
+	return words
+}
+
+// leet undoes common digit substitutions so "sh1t" is caught as well.
+var leet = strings.NewReplacer("0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "@", "a", "$", "s")
+
+func normalizeWord(s string) string {
+	return leet.Replace(strings.ToLower(strings.TrimSpace(s)))
+}
+
+// Variants lists alternatives to offer when an alias is taken, best first.
+func Variants(alias string, year int) []string {
+	variants := []string{
+		alias + strconv.Itoa(year),
+		"get" + alias,
+		"my" + alias,
+		alias + "hq",
+		alias + "app",
+		"go" + alias,
+		alias + "now",
+	}
+	for i := 2; i <= 9; i++ {
+		variants = append(variants, alias+strconv.Itoa(i))
+	}
+
+	return variants
+}
@@ -0,0 +1,27 @@
+package alias
+
+import (
+	"context"
+	"shorter/internal/utils"
+	"sync/atomic"
+)
+
+// Random picks base62 aliases of a fixed length with crypto/rand.
+type Random struct {
+	length atomic.Int64
+}
+
+func NewRandom(length int) *Random {
+	g := &Random{}
+	g.length.Store(int64(length))
+
+	return g
+}
+
+func (g *Random) Generate(ctx context.Context) (string, error) {
+	return utils.GenerateRandomAlias(int(g.length.Load()))
+}
+
+func (g *Random) Grow() {
+	g.length.Add(1)
+}
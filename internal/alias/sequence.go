@@ -0,0 +1,115 @@
+package alias
+
+import (
+	"context"
+	"errors"
+	"math/bits"
+	"sync/atomic"
+)
+
+const scatterPrime = 1_000_000_007
+
+var errNoSequence = errors.New("alias sequence is not configured")
+
+// Sequential encodes the next counter value in base62, so aliases never
+// collide with each other and only clash with custom ones.
+type Sequential struct {
+	seq   Sequence
+	width atomic.Int64
+}
+
+func NewSequential(seq Sequence, width int) *Sequential {
+	g := &Sequential{seq: seq}
+	g.width.Store(int64(width))
+
+	return g
+}
+
+func (g *Sequential) Generate(ctx context.Context) (string, error) {
+	if g.seq == nil {
+		return "", errNoSequence
+	}
+
+	n, err := g.seq(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	return encode(n, base62, int(g.width.Load())), nil
+}
+
+func (g *Sequential) Grow() {
+	g.width.Add(1)
+}
+
+// Hashids hides the counter behind a salted alphabet in the spirit of
+// hashids: aliases stay unique but consecutive links do not look alike.
+type Hashids struct {
+	seq      Sequence
+	salt     string
+	alphabet string
+	width    atomic.Int64
+}
+
+func NewHashids(seq Sequence, salt string, width int) *Hashids {
+	g := &Hashids{
+		seq:      seq,
+		salt:     salt,
+		alphabet: shuffle(base62, salt),
+	}
+	g.width.Store(int64(width))
+
+	return g
+}
+
+func (g *Hashids) Generate(ctx context.Context) (string, error) {
+	if g.seq == nil {
+		return "", errNoSequence
+	}
+
+	n, err := g.seq(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	// the lottery char picks a per-number alphabet, it is always the first
+	// char so numbers with different lotteries can not clash
+	lottery := g.alphabet[n%int64(len(g.alphabet))]
+	alphabet := shuffle(g.alphabet, string(lottery)+g.salt)
+
+	// scatter ids over the whole keyspace of the current width, the
+	// multiplier is coprime with 62 so the mapping stays one to one
+	width := max(int(g.width.Load())-1, 1)
+	space := uint64(1)
+	for range width {
+		space *= uint64(len(alphabet))
+	}
+	if uint64(n) < space {
+		hi, lo := bits.Mul64(uint64(n), scatterPrime)
+		n = int64(bits.Rem64(hi, lo, space))
+	}
+
+	return string(lottery) + encode(n, alphabet, width), nil
+}
+
+func (g *Hashids) Grow() {
+	g.width.Add(1)
+}
+
+// shuffle is the deterministic salt based shuffle used by hashids.
+func shuffle(alphabet, salt string) string {
+	if salt == "" {
+		return alphabet
+	}
+
+	a := []byte(alphabet)
+	for i, v, p := len(a)-1, 0, 0; i > 0; i, v = i-1, v+1 {
+		v %= len(salt)
+		n := int(salt[v])
+		p += n
+		j := (n + v + p) % i
+		a[i], a[j] = a[j], a[i]
+	}
+
+	return string(a)
+}
@@ -0,0 +1,80 @@
+package alias
+
+import (
+	"context"
+	"crypto/rand"
+	"math/big"
+	"strings"
+	"sync/atomic"
+)
+
+var adjectives = []string{
+	"amber", "bold", "brave", "bright", "calm", "clever", "cool", "cosmic",
+	"crisp", "dapper", "eager", "early", "fancy", "fast", "fresh", "gentle",
+	"giant", "glad", "golden", "grand", "green", "happy", "honest", "jolly",
+	"keen", "kind", "lively", "lucky", "mellow", "merry", "mighty", "modern",
+	"neat", "noble", "odd", "plain", "polite", "proud", "quick", "quiet",
+	"rapid", "rare", "ready", "royal", "rusty", "shiny", "silent", "silver",
+	"simple", "sleek", "smart", "snowy", "solid", "sunny", "super", "swift",
+	"tidy", "tiny", "urban", "vivid", "warm", "wild", "wise", "young",
+}
+
+var nouns = []string{
+	"apple", "badger", "beacon", "bear", "breeze", "brook", "canyon", "cedar",
+	"cloud", "comet", "coral", "crane", "delta", "dune", "eagle", "falcon",
+	"fern", "field", "forest", "fox", "garden", "glacier", "harbor", "hawk",
+	"heron", "hill", "island", "lake", "lark", "leaf", "lion", "maple",
+	"meadow", "moon", "moose", "oak", "ocean", "orbit", "otter", "owl",
+	"panda", "pebble", "pine", "planet", "pond", "puma", "rain", "raven",
+	"reef", "river", "rocket", "sea", "shadow", "sky", "spark", "star",
+	"stone", "storm", "tiger", "trail", "valley", "wave", "willow", "wolf",
+}
+
+// Words builds readable aliases like "brave-otter". Once pairs start to
+// collide, random digits are appended, one more per Grow.
+type Words struct {
+	digits atomic.Int64
+}
+
+func NewWords() *Words {
+	return &Words{}
+}
+
+func (g *Words) Generate(ctx context.Context) (string, error) {
+	adjective, err := pick(len(adjectives))
+	if err != nil {
+		return "", err
+	}
+	noun, err := pick(len(nouns))
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(adjectives[adjective])
+	sb.WriteByte('-')
+	sb.WriteString(nouns[noun])
+
+	for range g.digits.Load() {
+		d, err := pick(10)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(byte('0' + d))
+	}
+
+	return sb.String(), nil
+}
+
+func (g *Words) Grow() {
+	g.digits.Add(1)
+}
+
+func pick(n int) (int, error) {
+	idx, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+
+	return int(idx.Int64()), nil
+}
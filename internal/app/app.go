@@ -5,14 +5,30 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"shorter/internal/alias"
+	"shorter/internal/auth"
 	"shorter/internal/config"
 	"shorter/internal/consumer"
 	"shorter/internal/enricher"
+	"shorter/internal/export"
+	"shorter/internal/geo"
 	"shorter/internal/handler"
+	"shorter/internal/health"
+	"shorter/internal/live"
 	"shorter/internal/logger"
 	"shorter/internal/metrics"
+	"shorter/internal/model"
+	"shorter/internal/pages"
 	"shorter/internal/producer"
+	"shorter/internal/qr"
+	"shorter/internal/ratelimit"
 	"shorter/internal/repository"
+	"shorter/internal/request"
+	"shorter/internal/routing"
+	"shorter/internal/security"
+	"shorter/internal/urlpolicy"
+	"shorter/internal/webhook"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -29,6 +45,12 @@ type App struct {
 	Db            *pgxpool.Pool
 	KafkaProducer *producer.KafkaProducer
 	KafkaConsumer *consumer.KafkaConsumer
+	// fans click events out to webhooks in its own consumer group
+	WebhookConsumer   *consumer.WebhookConsumer
+	WebhookDispatcher *webhook.Dispatcher
+	ExportWorker      *export.Worker
+	// nil when health checks are disabled
+	HealthMonitor *health.Monitor
 }
 
 func NewApp() *App {
@@ -54,14 +76,24 @@ func NewApp() *App {
 	metrics.Register()
 
 	// repos
-	linkRepo := repository.NewLinkRepository(db)
+	linkRepo := repository.NewLinkRepository(db, cfg.Aliases.CaseInsensitive)
 	analyticsRepo := repository.NewAnalyticsRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	workspaceRepo := repository.NewWorkspaceRepository(db)
+	domainRepo := repository.NewDomainRepository(db)
+	healthRepo := repository.NewHealthRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	exportRepo := repository.NewExportRepository(db)
+
+	// saved clicks for live streams
+	liveHub := live.NewHub(cfg.Live.Buffer)
 
 	// kafka
 	kafkaProducer := producer.NewKafkaProducer(cfg.Kafka.Brokers, "click_events", logger)
+	deviceParser := enricher.NewDeviceParser()
 	enricher := enricher.NewIpGeoEnricher(
 		enricher.NewGeoClient(cfg.External.GeoAPIkey),
-		enricher.NewDeviceParser(),
+		deviceParser,
 	)
 	kafkaConsumer := consumer.NewKafkaConsumer(
 		cfg.Kafka.Brokers,
@@ -69,11 +101,97 @@ func NewApp() *App {
 		"shorter-consumer-group",
 		enricher,
 		analyticsRepo,
+		liveHub,
 		logger,
 	)
 
+	// webhooks
+	webhookDispatcher := webhook.NewDispatcher(
+		webhookRepo,
+		linkRepo,
+		domainRepo,
+		webhook.NewSender(cfg.Webhooks.Timeout, cfg.URLPolicy.BlockPrivateIPs),
+		cfg.Webhooks.MaxAttempts,
+		cfg.Webhooks.Backoff,
+		cfg.Webhooks.MaxBackoff,
+		cfg.Webhooks.Concurrency,
+		logger,
+	)
+	webhookConsumer := consumer.NewWebhookConsumer(
+		cfg.Kafka.Brokers,
+		"click_events",
+		"shorter-webhooks-group",
+		webhookDispatcher,
+		logger,
+	)
+
+	// html pages
+	pageRenderer, err := pages.NewRenderer(cfg.Pages.TemplatesDir)
+	if err != nil {
+		log.Fatalf("cannot load page templates: %v", err)
+	}
+
+	// security
+	if cfg.Security.CookieSecret == "" {
+		logger.Warn("security.cookie_secret is not set, unlock cookies will not survive restart")
+	}
+	signer, err := security.NewSigner(cfg.Security.CookieSecret)
+	if err != nil {
+		log.Fatalf("cannot create cookie signer: %v", err)
+	}
+	unlockLimiter := security.NewAttemptLimiter(cfg.Security.UnlockMaxAttempts, cfg.Security.UnlockWindow)
+
+	// destination url policy
+	urlPolicy, err := newURLPolicy(cfg, domainRepo)
+	if err != nil {
+		log.Fatalf("cannot load url policy: %v", err)
+	}
+
+	// aliases
+	aliasGenerator, err := alias.New(cfg.Aliases.Strategy, cfg.Aliases.Length, cfg.Aliases.Salt, linkRepo.NextAliasSequence)
+	if err != nil {
+		log.Fatalf("cannot create alias generator: %v", err)
+	}
+
+	denyWords, err := alias.LoadWordList(cfg.Aliases.DenyWordsFile)
+	if err != nil {
+		log.Fatalf("cannot load alias deny list: %v", err)
+	}
+	aliasPolicy := alias.NewPolicy(cfg.Aliases.Reserved, denyWords, cfg.Aliases.Brands)
+
+	// geo targeting
+	geoLocator, err := geo.NewLocator(cfg.Geo.DatabasePath, cfg.Geo.CountryHeader)
+	if err != nil {
+		log.Fatalf("cannot open geo database: %v", err)
+	}
+
+	router := routing.NewRouter(geoLocator, deviceParser)
+
+	// qr codes
+	qrRenderer, err := qr.NewRenderer(cfg.QR.LogoPath, cfg.QR.CacheSize)
+	if err != nil {
+		log.Fatalf("cannot create qr renderer: %v", err)
+	}
+
+	// client addresses
+	trustedProxies, err := request.ParseTrustedProxies(cfg.Server.TrustedProxies)
+	if err != nil {
+		log.Fatalf("cannot parse server.trusted_proxies: %v", err)
+	}
+
+	// rate limiting
+	limitStore := ratelimit.NewMemoryStore()
+	limit := func(name string, p config.RateLimitPolicy, key ratelimit.KeyFunc) func(http.Handler) http.Handler {
+		policy := ratelimit.Policy{Name: name, Requests: p.Requests, Period: p.Period, Burst: p.Burst}
+		return ratelimit.Middleware(limitStore, policy, key, logger)
+	}
+	createLimit := limit("create", cfg.RateLimit.Create, ratelimit.ByAPIKeyOrIP)
+	redirectLimit := limit("redirect", cfg.RateLimit.Redirect, ratelimit.ByIP)
+	statsLimit := limit("stats", cfg.RateLimit.Stats, ratelimit.ByAPIKeyOrIP)
+
 	// router
 	r := chi.NewRouter()
+	r.Use(request.TrustProxies(trustedProxies))
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
@@ -86,30 +204,118 @@ func NewApp() *App {
 		promhttp.Handler().ServeHTTP(w, r)
 	})
 
-	statsHandler := handler.NewStatsHandler(analyticsRepo, logger)
-	r.Get("/api/v1/stats/{alias}", statsHandler.Handle)
+	// api, requires "Authorization: Bearer <api key>"
+	// and works within a workspace picked by the X-Workspace-ID header
+	statsHandler := handler.NewStatsHandler(analyticsRepo, linkRepo, domainRepo, exportRepo, liveHub, logger, cfg)
+	exportHandler := handler.NewExportHandler(exportRepo, logger, cfg)
+	shorterHandler := handler.NewShorterHandler(linkRepo, domainRepo, aliasGenerator, aliasPolicy, urlPolicy, webhookDispatcher, logger, cfg)
+	linkHandler := handler.NewLinkHandler(linkRepo, domainRepo, healthRepo, webhookDispatcher, qrRenderer, router, logger, cfg)
+	workspaceHandler := handler.NewWorkspaceHandler(workspaceRepo, logger)
+	domainHandler := handler.NewDomainHandler(domainRepo, urlPolicy, logger, cfg)
+	aliasHandler := handler.NewAliasHandler(linkRepo, domainRepo, aliasPolicy, logger)
+	webhookHandler := handler.NewWebhookHandler(webhookRepo, urlPolicy, logger)
+
+	viewer := auth.RequireRole(model.RoleViewer)
+	editor := auth.RequireRole(model.RoleEditor)
+	admin := auth.RequireRole(model.RoleAdmin)
+	operator := auth.RequireOperator(cfg.Security.OperatorKeys)
+
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(auth.Middleware(apiKeyRepo, logger))
+
+		r.Get("/workspaces", workspaceHandler.List)
+		r.Post("/workspaces", workspaceHandler.Create)
+		r.Route("/workspaces/{workspaceID}/members/{keyID}", func(r chi.Router) {
+			r.Use(auth.Workspace(workspaceRepo, logger), admin)
+			r.Put("/", workspaceHandler.SetMember)
+			r.Delete("/", workspaceHandler.RemoveMember)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(auth.Workspace(workspaceRepo, logger))
+
+			r.With(viewer, statsLimit).Get("/stats/{alias}", statsHandler.Handle)
+			r.With(viewer, statsLimit).Get("/stats/{alias}/live", statsHandler.Live)
+			r.With(viewer, statsLimit).Get("/stats/{alias}/export", statsHandler.Export)
+			r.With(viewer).Get("/exports/{exportID}", exportHandler.Get)
+			r.With(viewer).Get("/exports/{exportID}/download", exportHandler.Download)
+
+			r.With(editor, createLimit).Post("/shorter", shorterHandler.Handle)
+			r.With(editor).Get("/aliases/{alias}/availability", aliasHandler.Availability)
+
+			r.With(viewer).Get("/domains", domainHandler.List)
+			r.With(admin, operator).Post("/domains", domainHandler.Create)
 
-	shorterHandler := handler.NewShorterHandler(linkRepo, logger, cfg)
-	r.Post("/api/v1/shorter", shorterHandler.Handle)
+			r.With(admin).Get("/webhooks", webhookHandler.List)
+			r.With(admin).Post("/webhooks", webhookHandler.Create)
+			r.With(admin).Delete("/webhooks/{webhookID}", webhookHandler.Delete)
+			r.With(admin).Get("/webhooks/{webhookID}/deliveries", webhookHandler.Deliveries)
+			r.With(admin).Post("/webhooks/{webhookID}/deliveries/{deliveryID}/replay", webhookHandler.Replay)
 
-	redirectHandler := handler.NewRedirectHandler(linkRepo, kafkaProducer, logger)
-	r.Get("/{alias}", redirectHandler.Handle)
+			r.With(viewer).Get("/links/{alias}", linkHandler.Get)
+			r.With(viewer).Get("/links/{alias}/qr", linkHandler.QR)
+			r.With(viewer).Post("/links/{alias}/dry-run", linkHandler.DryRun)
+			r.With(editor).Post("/links/{alias}/disable", linkHandler.Disable)
+			r.With(editor).Post("/links/{alias}/enable", linkHandler.Enable)
+		})
+	})
+
+	// public
+	redirectHandler := handler.NewRedirectHandler(
+		linkRepo,
+		domainRepo,
+		kafkaProducer,
+		router,
+		pageRenderer,
+		signer,
+		unlockLimiter,
+		logger,
+		cfg,
+	)
+	r.With(redirectLimit).Get("/", redirectHandler.Root)
+	r.With(redirectLimit).Get("/{alias}", redirectHandler.Handle)
+	r.With(redirectLimit).Post("/{alias}", redirectHandler.Unlock)
+
+	// links must not shadow routes
+	if err := aliasPolicy.ReserveRoutes(r); err != nil {
+		log.Fatalf("cannot reserve route aliases: %v", err)
+	}
+
+	// destination health checks
+	var healthMonitor *health.Monitor
+	if cfg.Health.Enabled {
+		healthMonitor = health.NewMonitor(
+			healthRepo,
+			health.NewChecker(cfg.Health.Timeout, cfg.Health.UserAgent, cfg.URLPolicy.BlockPrivateIPs),
+			cfg.Health.Interval,
+			cfg.Health.BatchSize,
+			cfg.Health.Concurrency,
+			cfg.Health.FailureThreshold,
+			logger,
+		)
+	}
 
 	// http
 	srv := &http.Server{
 		Addr:         cfg.Server.Host + ":" + fmt.Sprint(cfg.Server.Port),
-		Handler:      r,
+		Handler:      stripBasePath(cfg.BasePath(), r),
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
+	// live streams never finish on their own
+	srv.RegisterOnShutdown(liveHub.Close)
 
 	return &App{
-		Router:        r,
-		Server:        srv,
-		Logger:        logger,
-		Db:            db,
-		KafkaProducer: kafkaProducer,
-		KafkaConsumer: kafkaConsumer,
+		Router:            r,
+		Server:            srv,
+		Logger:            logger,
+		Db:                db,
+		KafkaProducer:     kafkaProducer,
+		KafkaConsumer:     kafkaConsumer,
+		WebhookConsumer:   webhookConsumer,
+		WebhookDispatcher: webhookDispatcher,
+		ExportWorker:      export.NewWorker(exportRepo, analyticsRepo, cfg.Export.Dir, cfg.Export.Retention, logger),
+		HealthMonitor:     healthMonitor,
 	}
 }
 
@@ -140,3 +346,51 @@ func connectDb(c *config.Config, logger *zap.Logger) (*pgxpool.Pool, error) {
 	logger.Info("Connected to PostgreSQL")
 	return db, nil
 }
+
+// stripBasePath lets the service run behind a reverse proxy mounted under
+// the public base path, whether or not the proxy removes the prefix.
+func stripBasePath(prefix string, next http.Handler) http.Handler {
+	if prefix == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == prefix || strings.HasPrefix(r.URL.Path, prefix+"/") {
+			r2 := r.Clone(r.Context())
+			r2.URL.Path = "/" + strings.TrimLeft(strings.TrimPrefix(r.URL.Path, prefix), "/")
+			r2.URL.RawPath = ""
+			next.ServeHTTP(w, r2)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func newURLPolicy(c *config.Config, domains repository.DomainRepository) (*urlpolicy.Engine, error) {
+	allow, err := urlpolicy.LoadDomainList(c.URLPolicy.AllowDomainsFile)
+	if err != nil {
+		return nil, fmt.Errorf("allow domains: %w", err)
+	}
+	deny, err := urlpolicy.LoadDomainList(c.URLPolicy.DenyDomainsFile)
+	if err != nil {
+		return nil, fmt.Errorf("deny domains: %w", err)
+	}
+
+	selfHosts := c.SelfHosts()
+
+	engine := urlpolicy.NewEngine(
+		urlpolicy.NewSchemeChecker(c.URLPolicy.AllowedSchemes),
+		urlpolicy.NewSelfHostChecker(func() []string { return selfHosts }),
+		urlpolicy.NewKnownHostChecker(func(ctx context.Context, host string) (bool, error) {
+			domain, err := domains.GetByHostname(ctx, host)
+			return domain != nil, err
+		}),
+		urlpolicy.NewDomainListChecker(allow, deny),
+	)
+	if c.URLPolicy.BlockPrivateIPs {
+		engine.Use(urlpolicy.NewPrivateIPChecker(c.URLPolicy.ResolveDNS))
+	}
+
+	return engine, nil
+}
@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+)
+
+const (
+	keyPrefix   = "shr_"
+	keyLength   = 40
+	keyAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+// GenerateKey returns a new random API key. Only its hash is stored, the
+// plain key is shown to the user once.
+func GenerateKey() (string, error) {
+	result := make([]byte, keyLength)
+	for i := range result {
+		idx, err := rand.Int(rand.Reader, big.NewInt(int64(len(keyAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		result[i] = keyAlphabet[idx.Int64()]
+	}
+
+	return keyPrefix + string(result), nil
+}
+
+// HashKey returns the hex SHA-256 of the key. Keys are long random strings,
+// so a fast hash is enough and lets us look them up by hash.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// KeyPrefix returns the visible part of the key used to tell keys apart.
+func KeyPrefix(key string) string {
+	if len(key) < len(keyPrefix)+6 {
+		return key
+	}
+
+	return key[:len(keyPrefix)+6]
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"shorter/internal/model"
+	"shorter/internal/repository"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+)
+
+type ctxKey struct{}
+
+// Middleware authenticates requests with an "Authorization: Bearer <key>"
+// header and stores the API key in the request context.
+func Middleware(repo repository.APIKeyRepository, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+			if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+				unauthorized(w, r)
+				return
+			}
+
+			key, err := repo.GetActiveByHash(r.Context(), HashKey(strings.TrimSpace(token)))
+			if err != nil {
+				logger.Error("failed to get api key", zap.Error(err))
+				w.WriteHeader(http.StatusInternalServerError)
+				render.JSON(w, r, render.M{"error": "internal error"})
+				return
+			}
+			if key == nil {
+				unauthorized(w, r)
+				return
+			}
+
+			go func(id int64) {
+				ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+				defer cancel()
+
+				if err := repo.TouchLastUsed(ctx, id); err != nil {
+					logger.Error("failed to update api key last use", zap.Error(err))
+				}
+			}(key.ID)
+
+			next.ServeHTTP(w, r.WithContext(WithKey(r.Context(), key)))
+		})
+	}
+}
+
+// RequireOperator lets through only the listed API keys, for actions that
+// affect the whole service rather than one workspace. Must run after
+// Middleware.
+func RequireOperator(keyIDs []int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := KeyFromContext(r.Context())
+			if key == nil || !slices.Contains(keyIDs, key.ID) {
+				w.WriteHeader(http.StatusForbidden)
+				render.JSON(w, r, render.M{"error": "operator key required"})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func WithKey(ctx context.Context, key *model.APIKey) context.Context {
+	return context.WithValue(ctx, ctxKey{}, key)
+}
+
+// KeyFromContext returns the authenticated API key, or nil on public routes.
+func KeyFromContext(ctx context.Context) *model.APIKey {
+	key, _ := ctx.Value(ctxKey{}).(*model.APIKey)
+	return key
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="shorter"`)
+	w.WriteHeader(http.StatusUnauthorized)
+	render.JSON(w, r, render.M{"error": "unauthorized"})
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"shorter/internal/model"
+	"shorter/internal/repository"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+)
+
+const WorkspaceHeader = "X-Workspace-ID"
+
+type membershipCtxKey struct{}
+
+// Workspace resolves the workspace a request works in and checks that the
+// authenticated key is a member of it. The workspace is taken from the
+// {workspaceID} URL param, then from the X-Workspace-ID header, and falls
+// back to the oldest workspace of the key. Must run after Middleware.
+func Workspace(repo repository.WorkspaceRepository, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := KeyFromContext(r.Context())
+			if key == nil {
+				unauthorized(w, r)
+				return
+			}
+
+			rawID := chi.URLParam(r, "workspaceID")
+			if rawID == "" {
+				rawID = r.Header.Get(WorkspaceHeader)
+			}
+
+			var (
+				membership *model.Membership
+				err        error
+			)
+			if rawID != "" {
+				workspaceID, parseErr := strconv.ParseInt(rawID, 10, 64)
+				if parseErr != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					render.JSON(w, r, render.M{"error": "invalid workspace id"})
+					return
+				}
+				membership, err = repo.GetMembership(r.Context(), workspaceID, key.ID)
+			} else {
+				membership, err = repo.GetDefaultMembership(r.Context(), key.ID)
+			}
+			if err != nil {
+				logger.Error("failed to get workspace membership", zap.Error(err))
+				w.WriteHeader(http.StatusInternalServerError)
+				render.JSON(w, r, render.M{"error": "internal error"})
+				return
+			}
+			if membership == nil {
+				w.WriteHeader(http.StatusForbidden)
+				render.JSON(w, r, render.M{"error": "not a member of the workspace"})
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithMembership(r.Context(), membership)))
+		})
+	}
+}
+
+// RequireRole rejects requests whose workspace role is lower than required.
+// Must run after Workspace.
+func RequireRole(required model.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			membership := MembershipFromContext(r.Context())
+			if membership == nil || !membership.Role.Allows(required) {
+				w.WriteHeader(http.StatusForbidden)
+				render.JSON(w, r, render.M{"error": "insufficient role, " + string(required) + " required"})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func WithMembership(ctx context.Context, membership *model.Membership) context.Context {
+	return context.WithValue(ctx, membershipCtxKey{}, membership)
+}
+
+// MembershipFromContext returns the workspace membership resolved by Workspace.
+func MembershipFromContext(ctx context.Context) *model.Membership {
+	m, _ := ctx.Value(membershipCtxKey{}).(*model.Membership)
+	return m
+}
@@ -1,6 +1,11 @@
 package config
 
 import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
 	"github.com/spf13/viper"
 )
 
@@ -10,6 +15,12 @@ type Config struct {
 	Server struct {
 		Host string `mapstructure:"host"`
 		Port int    `mapstructure:"port"`
+		// PublicBaseURL is how clients reach the service, e.g.
+		// https://example.com/s behind a proxy mounted under /s
+		PublicBaseURL string `mapstructure:"public_base_url"`
+		// TrustedProxies are addresses and CIDR ranges allowed to set
+		// X-Forwarded-For, it is ignored from anyone else
+		TrustedProxies []string `mapstructure:"trusted_proxies"`
 	} `mapstructure:"server"`
 
 	Kafka struct {
@@ -20,11 +31,111 @@ type Config struct {
 		URL string `mapstructure:"url"`
 	} `mapstructure:"db"`
 
+	Links struct {
+		ExhaustedRedirectURL string `mapstructure:"exhausted_redirect_url"`
+	} `mapstructure:"links"`
+
+	Aliases struct {
+		Strategy    string `mapstructure:"strategy"`
+		Length      int    `mapstructure:"length"`
+		Salt        string `mapstructure:"salt"`
+		MaxAttempts int    `mapstructure:"max_attempts"`
+		GrowAfter   int    `mapstructure:"grow_after"`
+
+		CaseInsensitive bool     `mapstructure:"case_insensitive"`
+		Reserved        []string `mapstructure:"reserved"`
+		DenyWordsFile   string   `mapstructure:"deny_words_file"`
+		Brands          []string `mapstructure:"brands"`
+	} `mapstructure:"aliases"`
+
+	URLPolicy struct {
+		AllowedSchemes   []string `mapstructure:"allowed_schemes"`
+		AllowDomainsFile string   `mapstructure:"allow_domains_file"`
+		DenyDomainsFile  string   `mapstructure:"deny_domains_file"`
+		BlockPrivateIPs  bool     `mapstructure:"block_private_ips"`
+		ResolveDNS       bool     `mapstructure:"resolve_dns"`
+		SelfHosts        []string `mapstructure:"self_hosts"`
+	} `mapstructure:"url_policy"`
+
+	QR struct {
+		LogoPath  string `mapstructure:"logo_path"`
+		CacheSize int    `mapstructure:"cache_size"`
+	} `mapstructure:"qr"`
+
+	Health struct {
+		Enabled          bool          `mapstructure:"enabled"`
+		Interval         time.Duration `mapstructure:"interval"`
+		Timeout          time.Duration `mapstructure:"timeout"`
+		BatchSize        int           `mapstructure:"batch_size"`
+		Concurrency      int           `mapstructure:"concurrency"`
+		FailureThreshold int           `mapstructure:"failure_threshold"`
+		UserAgent        string        `mapstructure:"user_agent"`
+	} `mapstructure:"health"`
+
+	Webhooks struct {
+		Timeout     time.Duration `mapstructure:"timeout"`
+		MaxAttempts int           `mapstructure:"max_attempts"`
+		Backoff     time.Duration `mapstructure:"backoff"`
+		MaxBackoff  time.Duration `mapstructure:"max_backoff"`
+		Concurrency int           `mapstructure:"concurrency"`
+	} `mapstructure:"webhooks"`
+
+	Live struct {
+		SnapshotInterval time.Duration `mapstructure:"snapshot_interval"`
+		Buffer           int           `mapstructure:"buffer"`
+		WriteTimeout     time.Duration `mapstructure:"write_timeout"`
+	} `mapstructure:"live"`
+
+	Export struct {
+		// where async export files are written, must be shared by all
+		// instances serving downloads
+		Dir string `mapstructure:"dir"`
+		// exports of more raw clicks run as background jobs
+		AsyncThreshold int64         `mapstructure:"async_threshold"`
+		Retention      time.Duration `mapstructure:"retention"`
+	} `mapstructure:"export"`
+
+	Pixels struct {
+		RedirectDelay time.Duration `mapstructure:"redirect_delay"`
+		AllowSnippets bool          `mapstructure:"allow_snippets"`
+	} `mapstructure:"pixels"`
+
+	Pages struct {
+		TemplatesDir string `mapstructure:"templates_dir"`
+	} `mapstructure:"pages"`
+
+	RateLimit struct {
+		Create   RateLimitPolicy `mapstructure:"create"`
+		Redirect RateLimitPolicy `mapstructure:"redirect"`
+		Stats    RateLimitPolicy `mapstructure:"stats"`
+	} `mapstructure:"rate_limit"`
+
+	Security struct {
+		CookieSecret      string        `mapstructure:"cookie_secret"`
+		UnlockMaxAttempts int           `mapstructure:"unlock_max_attempts"`
+		UnlockWindow      time.Duration `mapstructure:"unlock_window"`
+		// OperatorKeys are ids of API keys allowed to register domains
+		OperatorKeys []int64 `mapstructure:"operator_keys"`
+	} `mapstructure:"security"`
+
+	Geo struct {
+		DatabasePath  string `mapstructure:"database_path"`
+		CountryHeader string `mapstructure:"country_header"`
+	} `mapstructure:"geo"`
+
 	External struct {
 		GeoAPIkey string `mapstructure:"geo_api_key"`
 	} `mapstructure:"external"`
 }
 
+// RateLimitPolicy allows Requests per Period with bursts up to Burst.
+// Zero Requests disables the limit.
+type RateLimitPolicy struct {
+	Requests int           `mapstructure:"requests"`
+	Period   time.Duration `mapstructure:"period"`
+	Burst    int           `mapstructure:"burst"`
+}
+
 func LoadConfig(path string) (*Config, error) {
 	viper.AddConfigPath(path)
 	viper.SetConfigName("config")
@@ -41,5 +152,46 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, err
 	}
 
+	if err := config.normalizePublicBaseURL(); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
+
+// PublicURL returns the parsed public base URL.
+func (c *Config) PublicURL() *url.URL {
+	u, _ := url.Parse(c.Server.PublicBaseURL)
+	return u
+}
+
+// SelfHosts returns the hosts the service itself answers on.
+func (c *Config) SelfHosts() []string {
+	return append([]string{c.Server.Host, c.PublicURL().Host}, c.URLPolicy.SelfHosts...)
+}
+
+// BasePath returns the path prefix of the public base URL without the
+// trailing slash, empty when the service is mounted at the root.
+func (c *Config) BasePath() string {
+	return c.PublicURL().Path
+}
+
+func (c *Config) normalizePublicBaseURL() error {
+	if c.Server.PublicBaseURL == "" {
+		c.Server.PublicBaseURL = "http://" + c.Server.Host + ":" + fmt.Sprint(c.Server.Port)
+	}
+
+	u, err := url.Parse(c.Server.PublicBaseURL)
+	if err != nil {
+		return fmt.Errorf("server.public_base_url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return fmt.Errorf("server.public_base_url must be an absolute http(s) url, got %q", c.Server.PublicBaseURL)
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	u.RawQuery, u.Fragment = "", ""
+	c.Server.PublicBaseURL = u.String()
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"shorter/internal/enricher"
+	"shorter/internal/live"
 	"shorter/internal/metrics"
 	"shorter/internal/repository"
 	"sync"
@@ -17,6 +18,7 @@ type KafkaConsumer struct {
 	reader          *kafka.Reader
 	enricher        enricher.Enricher
 	repo            repository.AnalyticsRepository
+	hub             *live.Hub
 	logger          *zap.Logger
 	workerCount     int
 	shutdownTimeout time.Duration
@@ -28,6 +30,7 @@ func NewKafkaConsumer(
 	groupId string,
 	enricher enricher.Enricher,
 	repo repository.AnalyticsRepository,
+	hub *live.Hub,
 	logger *zap.Logger,
 ) *KafkaConsumer {
 	return &KafkaConsumer{
@@ -41,6 +44,7 @@ func NewKafkaConsumer(
 		}),
 		enricher:        enricher,
 		repo:            repo,
+		hub:             hub,
 		logger:          logger,
 		workerCount:     3,
 		shutdownTimeout: 30 * time.Second,
@@ -101,6 +105,7 @@ func (c *KafkaConsumer) worker(ctx context.Context, _ int) {
 			continue
 		}
 		metrics.EventsProcessed.Inc()
+		c.hub.Publish(enriched)
 		c.logger.Info("enrich click saved", zap.String("alias", event.Alias))
 
 	}
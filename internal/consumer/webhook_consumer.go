@@ -0,0 +1,82 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"shorter/internal/events"
+	"shorter/internal/model"
+	"shorter/internal/webhook"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.uber.org/zap"
+)
+
+// WebhookConsumer reads click events in its own consumer group and queues
+// click webhooks, independently of enrichment.
+type WebhookConsumer struct {
+	reader     *kafka.Reader
+	dispatcher *webhook.Dispatcher
+	logger     *zap.Logger
+}
+
+func NewWebhookConsumer(
+	brokers []string,
+	topic string,
+	groupId string,
+	dispatcher *webhook.Dispatcher,
+	logger *zap.Logger,
+) *WebhookConsumer {
+	return &WebhookConsumer{
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers:  brokers,
+			Topic:    topic,
+			GroupID:  groupId,
+			MinBytes: 10e3,
+			MaxBytes: 10e6,
+			MaxWait:  1 * time.Second,
+		}),
+		dispatcher: dispatcher,
+		logger:     logger,
+	}
+}
+
+func (c *WebhookConsumer) Start(ctx context.Context) error {
+	for {
+		msg, err := c.reader.ReadMessage(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				c.logger.Error("webhook consumer read error", zap.Error(err))
+			}
+			break
+		}
+
+		var event events.ClickEvent
+		if err := json.Unmarshal(msg.Value, &event); err != nil {
+			c.logger.Error("fail to unmarshall click event", zap.Error(err))
+			continue
+		}
+		// links created before workspaces have nobody to notify
+		if event.WorkspaceID == nil {
+			continue
+		}
+
+		c.handle(ctx, &event)
+	}
+
+	return c.reader.Close()
+}
+
+func (c *WebhookConsumer) handle(ctx context.Context, event *events.ClickEvent) {
+	data := webhook.NewClickData(event)
+	if err := c.dispatcher.Emit(ctx, *event.WorkspaceID, model.WebhookClickRecorded, data); err != nil {
+		c.logger.Error("failed to queue click.recorded", zap.Error(err), zap.String("alias", event.Alias))
+	}
+
+	if event.ClickCount > 0 {
+		data.Threshold = event.ClickCount
+		if err := c.dispatcher.EmitThreshold(ctx, *event.WorkspaceID, event.ClickCount, data); err != nil {
+			c.logger.Error("failed to queue click.threshold_reached", zap.Error(err), zap.String("alias", event.Alias))
+		}
+	}
+}
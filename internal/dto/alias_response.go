@@ -0,0 +1,10 @@
+package dto
+
+type AliasAvailabilityResponse struct {
+	Alias       string   `json:"alias"`
+	Domain      string   `json:"domain,omitempty"`
+	Available   bool     `json:"available"`
+	Rule        string   `json:"rule,omitempty"`
+	Reason      string   `json:"reason,omitempty"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
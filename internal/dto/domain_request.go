@@ -0,0 +1,7 @@
+package dto
+
+type DomainRequest struct {
+	Hostname    string `json:"hostname" validate:"required,fqdn,max=255"`
+	FallbackUrl string `json:"fallback_url,omitempty" validate:"omitempty,url"`
+	NotFoundUrl string `json:"not_found_url,omitempty" validate:"omitempty,url"`
+}
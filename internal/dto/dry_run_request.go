@@ -0,0 +1,27 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// DryRunRequest evaluates a simulated click against a link. Rules, when
+// given, replace the link's routing rules so they can be tried before saving.
+type DryRunRequest struct {
+	Rules   json.RawMessage  `json:"rules,omitempty"`
+	Request SimulatedRequest `json:"request"`
+}
+
+type SimulatedRequest struct {
+	IP             string            `json:"ip,omitempty" validate:"omitempty,ip"`
+	Country        string            `json:"country,omitempty" validate:"omitempty,iso3166_1_alpha2"`
+	UserAgent      string            `json:"user_agent,omitempty"`
+	AcceptLanguage string            `json:"accept_language,omitempty"`
+	Referer        string            `json:"referer,omitempty" validate:"omitempty,url"`
+	Time           *time.Time        `json:"time,omitempty"`
+	Query          map[string]string `json:"query,omitempty"`
+}
+
+func (r *DryRunRequest) HasRules() bool {
+	return len(r.Rules) > 0 && string(r.Rules) != "null"
+}
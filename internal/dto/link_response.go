@@ -0,0 +1,31 @@
+package dto
+
+import (
+	"shorter/internal/model"
+	"time"
+)
+
+type LinkResponse struct {
+	Alias        string              `json:"alias"`
+	ShortUrl     string              `json:"short_url"`
+	Domain       string              `json:"domain,omitempty"`
+	OriginalUrl  string              `json:"original_url"`
+	Status       string              `json:"status"`
+	CreatedAt    time.Time           `json:"created_at"`
+	ActiveFrom   *time.Time          `json:"active_from,omitempty"`
+	ExpiresAt    *time.Time          `json:"expires_at,omitempty"`
+	ClickCount   int                 `json:"click_count"`
+	MaxClicks    *int                `json:"max_clicks,omitempty"`
+	Protected    bool                `json:"protected"`
+	WorkspaceID  *int64              `json:"workspace_id,omitempty"`
+	GeoRules     []GeoRule           `json:"geo_rules,omitempty"`
+	DeviceRules  []DeviceRule        `json:"device_rules,omitempty"`
+	RoutingRules []model.RoutingRule `json:"routing_rules,omitempty"`
+	Variants     []Variant           `json:"variants,omitempty"`
+	Sticky       bool                `json:"sticky_variants,omitempty"`
+	Preview      bool                `json:"preview"`
+	Meta         *LinkMeta           `json:"meta,omitempty"`
+	Pixels       []Pixel             `json:"pixels,omitempty"`
+	PixelDelayMs *int                `json:"pixel_delay_ms,omitempty"`
+	Health       *model.LinkHealth   `json:"health,omitempty"`
+}
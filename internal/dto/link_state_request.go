@@ -0,0 +1,5 @@
+package dto
+
+type LinkStateRequest struct {
+	Reason string `json:"reason" validate:"required,max=1000"`
+}
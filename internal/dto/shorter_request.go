@@ -1,7 +1,87 @@
 package dto
 
+import (
+	"encoding/json"
+	"time"
+)
+
 type ShorterRequest struct {
-	OriginalUrl string `json:"original_url" validate:"required,url"`
-	CustomAlias string `json:"custom_alias,omitempty" validate:"omitempty,alphanum,min=3,max=100"`
-	ExpiresIn   *int   `json:"expires_in,omitempty"`
+	OriginalUrl          string       `json:"original_url" validate:"required,url"`
+	CustomAlias          string       `json:"custom_alias,omitempty" validate:"omitempty,alphanum,min=3,max=100"`
+	Domain               string       `json:"domain,omitempty" validate:"omitempty,fqdn"`
+	ActiveFrom           *time.Time   `json:"active_from,omitempty"`
+	ScheduledRedirectUrl string       `json:"scheduled_redirect_url,omitempty" validate:"omitempty,url"`
+	ExpiresIn            *int         `json:"expires_in,omitempty"`
+	ExpiredRedirectUrl   string       `json:"expired_redirect_url,omitempty" validate:"omitempty,url"`
+	Password             string       `json:"password,omitempty" validate:"omitempty,min=4,max=72"`
+	MaxClicks            *int         `json:"max_clicks,omitempty" validate:"omitempty,min=1"`
+	ReuseExisting        bool         `json:"reuse_existing,omitempty"`
+	GeoRules             []GeoRule    `json:"geo_rules,omitempty" validate:"omitempty,max=50,dive"`
+	DeviceRules          []DeviceRule `json:"device_rules,omitempty" validate:"omitempty,max=10,dive"`
+	Variants             []Variant    `json:"variants,omitempty" validate:"omitempty,min=2,max=10,unique=Name,dive"`
+	StickyVariants       bool         `json:"sticky_variants,omitempty"`
+	// validated against the routing rules JSON schema
+	RoutingRules json.RawMessage `json:"routing_rules,omitempty"`
+	Preview      bool            `json:"preview,omitempty"`
+	Meta         *LinkMeta       `json:"meta,omitempty"`
+	Pixels       []Pixel         `json:"pixels,omitempty" validate:"omitempty,max=10,dive"`
+	PixelDelayMs *int            `json:"pixel_delay_ms,omitempty" validate:"omitempty,min=0,max=10000"`
+}
+
+// Pixel is an image or script URL, or a raw HTML snippet when snippets are
+// enabled.
+type Pixel struct {
+	Type    string `json:"type" validate:"required,oneof=image script html"`
+	Url     string `json:"url,omitempty" validate:"omitempty,url,max=2048"`
+	Snippet string `json:"snippet,omitempty" validate:"max=10000"`
+}
+
+// LinkMeta is the OpenGraph and Twitter card served to link preview bots.
+type LinkMeta struct {
+	Title       string `json:"title,omitempty" validate:"max=200"`
+	Description string `json:"description,omitempty" validate:"max=500"`
+	Image       string `json:"image,omitempty" validate:"omitempty,url,max=2048"`
+}
+
+// GeoRule maps ISO country codes like "DE" to an alternate destination.
+type GeoRule struct {
+	Countries []string `json:"countries" validate:"required,min=1,dive,iso3166_1_alpha2"`
+	Url       string   `json:"url" validate:"required,url"`
+}
+
+// IsPlain reports whether the link has no one-off settings, only plain
+// links are deduplicated.
+func (r *ShorterRequest) IsPlain() bool {
+	return r.CustomAlias == "" &&
+		r.ActiveFrom == nil &&
+		r.ExpiresIn == nil &&
+		r.Password == "" &&
+		r.MaxClicks == nil &&
+		len(r.GeoRules) == 0 &&
+		len(r.DeviceRules) == 0 &&
+		len(r.Variants) == 0 &&
+		!r.HasRoutingRules() &&
+		!r.Preview &&
+		r.Meta == nil &&
+		len(r.Pixels) == 0
+}
+
+func (r *ShorterRequest) HasRoutingRules() bool {
+	return len(r.RoutingRules) > 0 && string(r.RoutingRules) != "null"
+}
+
+// DeviceRule targets ios, android, mobile or desktop visitors. AppUrl is an
+// app URI like "myapp://product/42", Url the store or web fallback.
+type DeviceRule struct {
+	Platforms []string `json:"platforms" validate:"required,min=1,dive,oneof=ios android mobile desktop"`
+	Url       string   `json:"url" validate:"required,url"`
+	AppUrl    string   `json:"app_url,omitempty" validate:"omitempty,url"`
+}
+
+// Variant is a weighted destination, visitors are split between variants
+// in proportion to their weights.
+type Variant struct {
+	Name   string `json:"name" validate:"required,alphanum,max=32"`
+	Url    string `json:"url" validate:"required,url"`
+	Weight int    `json:"weight" validate:"min=1,max=1000"`
 }
@@ -1,6 +1,10 @@
 package dto
 
 type ShorterResponse struct {
-	ShortUrl  string `json:"short_url"`
-	ExpiresAt string `json:"expires_at,omitempty"`
+	ShortUrl   string `json:"short_url"`
+	Status     string `json:"status"`
+	ActiveFrom string `json:"active_from,omitempty"`
+	ExpiresAt  string `json:"expires_at,omitempty"`
+	MaxClicks  *int   `json:"max_clicks,omitempty"`
+	Reused     bool   `json:"reused,omitempty"`
 }
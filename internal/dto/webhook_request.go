@@ -0,0 +1,7 @@
+package dto
+
+type WebhookRequest struct {
+	Url             string   `json:"url" validate:"required,url,max=2048"`
+	Events          []string `json:"events" validate:"required,min=1,unique,dive,oneof=link.created link.updated link.expired click.recorded click.threshold_reached"`
+	ClickThresholds []int    `json:"click_thresholds,omitempty" validate:"max=20,unique,dive,min=1"`
+}
@@ -0,0 +1,9 @@
+package dto
+
+type WorkspaceRequest struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+type WorkspaceMemberRequest struct {
+	Role string `json:"role" validate:"required,oneof=admin editor viewer"`
+}
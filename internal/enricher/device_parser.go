@@ -1,6 +1,11 @@
 package enricher
 
-import "github.com/mssola/user_agent"
+import (
+	"shorter/internal/model"
+	"strings"
+
+	"github.com/mssola/user_agent"
+)
 
 type DeviceParser struct{}
 
@@ -22,4 +27,22 @@ func (d *DeviceParser) Parse(userAgent string) (device, os, browser string) {
 	browser, _ = parser.Browser()
 
 	return device, os, browser
-}
\ No newline at end of file
+}
+
+// Platform classifies the user agent for targeting: ios, android, mobile for
+// other phones and tablets, or desktop.
+func (d *DeviceParser) Platform(userAgent string) string {
+	device, os, _ := d.Parse(userAgent)
+
+	switch {
+	case strings.Contains(os, "Android"):
+		return model.PlatformAndroid
+	// iPhone and iPad report "CPU [iPhone] OS 17_0 like Mac OS X"
+	case strings.Contains(os, "like Mac OS X"):
+		return model.PlatformIOS
+	case device == "mobile":
+		return model.PlatformMobile
+	default:
+		return model.PlatformDesktop
+	}
+}
@@ -9,14 +9,17 @@ type Enricher interface {
 }
 
 type ClickTask struct {
+	LinkID    int64  `json:"link_id"`
 	Alias     string `json:"alias"`
 	IP        string `json:"ip"`
 	UserAgent string `json:"user_agent"`
 	Referer   string `json:"referer"`
 	Timestamp string `json:"timestamp"`
+	Variant   string `json:"variant"`
 }
 
 type EnrichedClick struct {
+	LinkID    int64   `db:"link_id"`
 	Alias     string  `db:"alias"`
 	IP        string  `db:"ip"`
 	Country   *string `db:"country"`
@@ -26,4 +29,5 @@ type EnrichedClick struct {
 	Browser   string  `db:"browser"`
 	Referer   *string `db:"referer"`
 	Timestamp string  `db:"timestamp"`
+	Variant   *string `db:"variant"`
 }
@@ -41,7 +41,13 @@ func (e *IpGeoEnricher) Enrich(ctx context.Context, task *ClickTask) (*EnrichedC
 		refererPtr = &referer
 	}
 
+	var variant *string
+	if task.Variant != "" {
+		variant = &task.Variant
+	}
+
 	return &EnrichedClick{
+		LinkID:    task.LinkID,
 		Alias:     task.Alias,
 		IP:        task.IP,
 		Country:   country,
@@ -51,5 +57,6 @@ func (e *IpGeoEnricher) Enrich(ctx context.Context, task *ClickTask) (*EnrichedC
 		Browser:   browser,
 		Referer:   refererPtr,
 		Timestamp: task.Timestamp,
+		Variant:   variant,
 	}, nil
 }
@@ -3,9 +3,14 @@ package events
 import "time"
 
 type ClickEvent struct {
-	Alias     string    `json:"alias"`
-	Timestamp time.Time `json:"timestamp"`
-	IP        string    `json:"ip"`
-	UserAgent string    `json:"user_agent"`
-	Referer   string    `json:"referer,omitempty"`
+	LinkID      int64     `json:"link_id"`
+	WorkspaceID *int64    `json:"workspace_id,omitempty"`
+	Alias       string    `json:"alias"`
+	Timestamp   time.Time `json:"timestamp"`
+	IP          string    `json:"ip"`
+	UserAgent   string    `json:"user_agent"`
+	Referer     string    `json:"referer,omitempty"`
+	Variant     string    `json:"variant,omitempty"`
+	// value of the link click counter after this click, 0 if unknown
+	ClickCount int `json:"click_count,omitempty"`
 }
@@ -0,0 +1,77 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"shorter/internal/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	clickHeader     = []string{"timestamp", "alias", "ip", "country", "city", "device", "os", "browser", "referer", "variant"}
+	aggregateHeader = []string{"period", "country", "device", "clicks", "unique_ips"}
+)
+
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func newCSVWriter(w io.Writer, aggregated bool) (*csvWriter, error) {
+	header := clickHeader
+	if aggregated {
+		header = aggregateHeader
+	}
+
+	cw := &csvWriter{w: csv.NewWriter(w), record: make([]string, len(header))}
+	if err := cw.w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return cw, nil
+}
+
+func (c *csvWriter) WriteClick(row *model.ClickRow) error {
+	c.record[0] = row.Timestamp.UTC().Format(time.RFC3339Nano)
+	c.record[1] = cell(&row.Alias)
+	c.record[2] = cell(row.IP)
+	c.record[3] = cell(row.Country)
+	c.record[4] = cell(row.City)
+	c.record[5] = cell(row.Device)
+	c.record[6] = cell(row.OS)
+	c.record[7] = cell(row.Browser)
+	c.record[8] = cell(row.Referer)
+	c.record[9] = cell(row.Variant)
+
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) WriteAggregate(row *model.ClickAggregate) error {
+	c.record[0] = row.Period.Format(time.RFC3339)
+	c.record[1] = cell(row.Country)
+	c.record[2] = cell(row.Device)
+	c.record[3] = strconv.FormatInt(row.Clicks, 10)
+	c.record[4] = strconv.FormatInt(row.UniqueIPs, 10)
+
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// cell renders a text value. Values a spreadsheet would run as a formula,
+// like a referer starting with "=", are quoted with a leading apostrophe.
+func cell(v *string) string {
+	if v == nil {
+		return ""
+	}
+	if *v != "" && strings.ContainsRune("=+-@\t\r", rune((*v)[0])) {
+		return "'" + *v
+	}
+
+	return *v
+}
@@ -0,0 +1,36 @@
+package export
+
+import (
+	"context"
+	"io"
+	"shorter/internal/model"
+	"shorter/internal/repository"
+)
+
+// Run streams the rows of the query to w in the query format and returns
+// how many rows were written.
+func Run(ctx context.Context, repo repository.AnalyticsRepository, q *model.ExportQuery, w io.Writer) (int64, error) {
+	aggregated := q.Aggregate != ""
+	out, err := NewWriter(q.Format, aggregated, w)
+	if err != nil {
+		return 0, err
+	}
+
+	var rows int64
+	if aggregated {
+		err = repo.StreamAggregates(ctx, q, func(row *model.ClickAggregate) error {
+			rows++
+			return out.WriteAggregate(row)
+		})
+	} else {
+		err = repo.StreamClicks(ctx, q, func(row *model.ClickRow) error {
+			rows++
+			return out.WriteClick(row)
+		})
+	}
+	if err != nil {
+		return rows, err
+	}
+
+	return rows, out.Close()
+}
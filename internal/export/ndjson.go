@@ -0,0 +1,30 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"shorter/internal/model"
+)
+
+type ndjsonWriter struct {
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	buf := bufio.NewWriter(w)
+	return &ndjsonWriter{buf: buf, enc: json.NewEncoder(buf)}
+}
+
+func (n *ndjsonWriter) WriteClick(row *model.ClickRow) error {
+	return n.enc.Encode(row)
+}
+
+func (n *ndjsonWriter) WriteAggregate(row *model.ClickAggregate) error {
+	return n.enc.Encode(row)
+}
+
+func (n *ndjsonWriter) Close() error {
+	return n.buf.Flush()
+}
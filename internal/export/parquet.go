@@ -0,0 +1,90 @@
+package export
+
+import (
+	"io"
+	"shorter/internal/model"
+
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// rows are buffered up to a row group before they are written out
+const parquetRowGroupSize = 8 << 20
+
+type parquetClick struct {
+	Timestamp int64   `parquet:"name=timestamp, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	Alias     string  `parquet:"name=alias, type=BYTE_ARRAY, convertedtype=UTF8"`
+	IP        *string `parquet:"name=ip, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	Country   *string `parquet:"name=country, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	City      *string `parquet:"name=city, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	Device    *string `parquet:"name=device, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	OS        *string `parquet:"name=os, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	Browser   *string `parquet:"name=browser, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	Referer   *string `parquet:"name=referer, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	Variant   *string `parquet:"name=variant, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+}
+
+type parquetAggregate struct {
+	Period    int64   `parquet:"name=period, type=INT64, convertedtype=TIMESTAMP_MILLIS"`
+	Country   *string `parquet:"name=country, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	Device    *string `parquet:"name=device, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL"`
+	Clicks    int64   `parquet:"name=clicks, type=INT64"`
+	UniqueIPs int64   `parquet:"name=unique_ips, type=INT64"`
+}
+
+type parquetWriter struct {
+	pw *writer.ParquetWriter
+}
+
+func newParquetWriter(w io.Writer, aggregated bool) (*parquetWriter, error) {
+	var schema any = new(parquetClick)
+	if aggregated {
+		schema = new(parquetAggregate)
+	}
+
+	pw, err := writer.NewParquetWriterFromWriter(w, schema, 1)
+	if err != nil {
+		return nil, err
+	}
+	pw.RowGroupSize = parquetRowGroupSize
+
+	return &parquetWriter{pw: pw}, nil
+}
+
+// the writer keeps rows until the row group is flushed, so they are
+// copied rather than pointing into the caller's reused row
+func (p *parquetWriter) WriteClick(row *model.ClickRow) error {
+	return p.pw.Write(parquetClick{
+		Timestamp: row.Timestamp.UnixMilli(),
+		Alias:     row.Alias,
+		IP:        clone(row.IP),
+		Country:   clone(row.Country),
+		City:      clone(row.City),
+		Device:    clone(row.Device),
+		OS:        clone(row.OS),
+		Browser:   clone(row.Browser),
+		Referer:   clone(row.Referer),
+		Variant:   clone(row.Variant),
+	})
+}
+
+func (p *parquetWriter) WriteAggregate(row *model.ClickAggregate) error {
+	return p.pw.Write(parquetAggregate{
+		Period:    row.Period.UnixMilli(),
+		Country:   clone(row.Country),
+		Device:    clone(row.Device),
+		Clicks:    row.Clicks,
+		UniqueIPs: row.UniqueIPs,
+	})
+}
+
+func (p *parquetWriter) Close() error {
+	return p.pw.WriteStop()
+}
+
+func clone(v *string) *string {
+	if v == nil {
+		return nil
+	}
+	c := *v
+	return &c
+}
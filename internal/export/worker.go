@@ -0,0 +1,159 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"shorter/internal/model"
+	"shorter/internal/repository"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultRetention = 24 * time.Hour
+
+	pollInterval    = 5 * time.Second
+	cleanupInterval = 10 * time.Minute
+	// a job running longer is considered abandoned and started over
+	jobTimeout = time.Hour
+)
+
+// Worker runs export jobs in the background, writing files to dir that are
+// kept for the retention period.
+type Worker struct {
+	repo      repository.ExportRepository
+	analytics repository.AnalyticsRepository
+	dir       string
+	retention time.Duration
+	logger    *zap.Logger
+}
+
+func NewWorker(
+	repo repository.ExportRepository,
+	analytics repository.AnalyticsRepository,
+	dir string,
+	retention time.Duration,
+	logger *zap.Logger,
+) *Worker {
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "shorter-exports")
+	}
+	if retention <= 0 {
+		retention = defaultRetention
+	}
+
+	return &Worker{
+		repo:      repo,
+		analytics: analytics,
+		dir:       dir,
+		retention: retention,
+		logger:    logger,
+	}
+}
+
+// Start runs until ctx is cancelled.
+func (w *Worker) Start(ctx context.Context) error {
+	if err := os.MkdirAll(w.dir, 0o750); err != nil {
+		return fmt.Errorf("export dir: %w", err)
+	}
+	w.logger.Info("starting export worker", zap.String("dir", w.dir))
+
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+	cleanup := time.NewTicker(cleanupInterval)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-poll.C:
+			w.runPending(ctx)
+		case <-cleanup.C:
+			w.cleanup(ctx)
+		}
+	}
+}
+
+func (w *Worker) runPending(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := w.repo.Claim(ctx, time.Now().Add(-jobTimeout))
+		if err != nil {
+			if ctx.Err() == nil {
+				w.logger.Error("failed to claim export job", zap.Error(err))
+			}
+			return
+		}
+		if job == nil {
+			return
+		}
+
+		w.run(ctx, job)
+	}
+}
+
+func (w *Worker) run(ctx context.Context, job *model.ExportJob) {
+	jobCtx, cancel := context.WithTimeout(ctx, jobTimeout)
+	defer cancel()
+
+	path := filepath.Join(w.dir, fmt.Sprintf("%d-%s", job.ID, FileName(&job.ExportQuery)))
+	rows, err := w.writeFile(jobCtx, job, path)
+	if err != nil {
+		if ctx.Err() != nil {
+			// shutting down, the job is picked up again once stale
+			return
+		}
+		w.logger.Error("export job failed", zap.Error(err), zap.Int64("job_id", job.ID))
+		if err := w.repo.Fail(ctx, job.ID, err.Error(), time.Now().Add(w.retention)); err != nil {
+			w.logger.Error("failed to mark export job failed", zap.Error(err), zap.Int64("job_id", job.ID))
+		}
+		return
+	}
+
+	if err := w.repo.Finish(ctx, job.ID, path, rows, time.Now().Add(w.retention)); err != nil {
+		w.logger.Error("failed to finish export job", zap.Error(err), zap.Int64("job_id", job.ID))
+		return
+	}
+	w.logger.Info("export job done", zap.Int64("job_id", job.ID), zap.Int64("rows", rows))
+}
+
+// writeFile writes the export next to its final path and renames it once
+// complete, so a download never sees a partial file.
+func (w *Worker) writeFile(ctx context.Context, job *model.ExportJob, path string) (int64, error) {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp)
+
+	rows, err := Run(ctx, w.analytics, &job.ExportQuery, f)
+	if err != nil {
+		f.Close()
+		return 0, err
+	}
+	if err := f.Close(); err != nil {
+		return 0, err
+	}
+
+	return rows, os.Rename(tmp, path)
+}
+
+func (w *Worker) cleanup(ctx context.Context) {
+	paths, err := w.repo.DeleteExpired(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.logger.Error("failed to delete expired export jobs", zap.Error(err))
+		}
+		return
+	}
+
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			w.logger.Error("failed to remove export file", zap.Error(err), zap.String("path", path))
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"shorter/internal/model"
+)
+
+// Writer encodes export rows in one of the supported formats. Close must
+// be called to complete the output.
+type Writer interface {
+	WriteClick(row *model.ClickRow) error
+	WriteAggregate(row *model.ClickAggregate) error
+	Close() error
+}
+
+// NewWriter returns a writer for raw clicks or, with aggregated, for
+// aggregate rows.
+func NewWriter(format string, aggregated bool, w io.Writer) (Writer, error) {
+	switch format {
+	case model.ExportCSV:
+		return newCSVWriter(w, aggregated)
+	case model.ExportNDJSON:
+		return newNDJSONWriter(w), nil
+	case model.ExportParquet:
+		return newParquetWriter(w, aggregated)
+	}
+
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+func IsFormat(format string) bool {
+	return format == model.ExportCSV || format == model.ExportNDJSON || format == model.ExportParquet
+}
+
+func ContentType(format string) string {
+	switch format {
+	case model.ExportCSV:
+		return "text/csv; charset=utf-8"
+	case model.ExportNDJSON:
+		return "application/x-ndjson"
+	}
+
+	return "application/vnd.apache.parquet"
+}
+
+// FileName names the export of the query for downloads.
+func FileName(q *model.ExportQuery) string {
+	kind := "clicks"
+	if q.Aggregate != "" {
+		kind = "clicks-by-" + q.Aggregate
+	}
+
+	return q.Alias + "-" + kind + "." + q.Format
+}
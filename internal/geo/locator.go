@@ -0,0 +1,71 @@
+package geo
+
+import (
+	"net"
+	"net/http"
+	"shorter/internal/request"
+	"strings"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// Locator resolves the visitor's country on the request path from a local
+// MaxMind database, the external geo API is far too slow for redirects.
+type Locator struct {
+	db     CountryReader
+	header string
+}
+
+// CountryReader looks up the country of an address, *geoip2.Reader is one.
+type CountryReader interface {
+	Country(ip net.IP) (*geoip2.Country, error)
+}
+
+// NewLocator opens the country database. An empty path gives a locator
+// that only trusts the country header set by trusted proxies, if any.
+func NewLocator(dbPath, header string) (*Locator, error) {
+	l := &Locator{header: header}
+	if dbPath == "" {
+		return l, nil
+	}
+
+	db, err := geoip2.Open(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewReaderLocator(db, header), nil
+}
+
+// NewReaderLocator builds a locator on an already opened country database.
+func NewReaderLocator(db CountryReader, header string) *Locator {
+	return &Locator{db: db, header: header}
+}
+
+// Country returns the ISO 3166-1 alpha-2 code of the client, or "" when it
+// is unknown.
+func (l *Locator) Country(r *http.Request) string {
+	// a CDN in front of the service already knows the country, anyone else
+	// could pick their own
+	if l.header != "" && request.FromTrustedProxy(r) {
+		if code := strings.ToUpper(strings.TrimSpace(r.Header.Get(l.header))); len(code) == 2 && code != "XX" {
+			return code
+		}
+	}
+
+	if l.db == nil {
+		return ""
+	}
+
+	ip := net.ParseIP(request.ClientIP(r))
+	if ip == nil {
+		return ""
+	}
+
+	record, err := l.db.Country(ip)
+	if err != nil {
+		return ""
+	}
+
+	return record.Country.IsoCode
+}
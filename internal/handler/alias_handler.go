@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"net/http"
+	"shorter/internal/alias"
+	"shorter/internal/auth"
+	"shorter/internal/dto"
+	"shorter/internal/repository"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+)
+
+const (
+	maxAliasSuggestions = 5
+	// same rules as dto.ShorterRequest.CustomAlias
+	customAliasRules = "alphanum,min=3,max=100"
+)
+
+type AliasHandler struct {
+	repo    repository.LinkRepository
+	domains repository.DomainRepository
+	policy  *alias.Policy
+	logger  *zap.Logger
+}
+
+func NewAliasHandler(
+	repo repository.LinkRepository,
+	domains repository.DomainRepository,
+	policy *alias.Policy,
+	logger *zap.Logger,
+) *AliasHandler {
+	return &AliasHandler{
+		repo:    repo,
+		domains: domains,
+		policy:  policy,
+		logger:  logger,
+	}
+}
+
+// Availability tells whether a custom alias can be taken on a domain and
+// suggests free alternatives when it can not.
+func (h *AliasHandler) Availability(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "alias")
+	if err := validate.Var(name, customAliasRules); err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		render.JSON(w, r, render.M{"errors": render.M{"alias": "alias must be 3-100 letters or digits"}})
+		return
+	}
+
+	workspace := auth.MembershipFromContext(r.Context())
+	hostname := r.URL.Query().Get("domain")
+	domain, ok, err := workspaceDomain(r.Context(), h.domains, workspace.WorkspaceID, hostname)
+	if err != nil {
+		h.logger.Error("failed to get domain", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		render.JSON(w, r, render.M{"error": "domain not found"})
+		return
+	}
+
+	// the alias itself goes first, the rest are candidates for suggestions
+	candidates := []string{name}
+	for _, v := range alias.Variants(name, time.Now().Year()) {
+		if validate.Var(v, customAliasRules) == nil && h.policy.Check(v) == nil {
+			candidates = append(candidates, v)
+		}
+	}
+
+	taken, err := h.repo.TakenAliases(r.Context(), domainID(domain), candidates)
+	if err != nil {
+		h.logger.Error("failed to check aliases", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return
+	}
+
+	resp := dto.AliasAvailabilityResponse{
+		Alias:     name,
+		Domain:    hostname,
+		Available: true,
+	}
+	if err := h.policy.Check(name); err != nil {
+		rejection, _ := alias.AsRejection(err)
+		resp.Available = false
+		resp.Rule = rejection.Rule
+		resp.Reason = rejection.Reason
+	} else if taken[name] {
+		resp.Available = false
+		resp.Rule = "taken"
+		resp.Reason = "alias already in use"
+	}
+
+	if !resp.Available {
+		for _, c := range candidates[1:] {
+			if !taken[c] && len(resp.Suggestions) < maxAliasSuggestions {
+				resp.Suggestions = append(resp.Suggestions, c)
+			}
+		}
+	}
+
+	render.JSON(w, r, resp)
+}
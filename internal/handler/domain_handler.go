@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"shorter/internal/auth"
+	"shorter/internal/config"
+	"shorter/internal/dto"
+	"shorter/internal/model"
+	"shorter/internal/repository"
+	"shorter/internal/urlpolicy"
+	"strings"
+
+	"github.com/go-chi/render"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+)
+
+type DomainHandler struct {
+	repo   repository.DomainRepository
+	policy *urlpolicy.Engine
+	logger *zap.Logger
+	cfg    *config.Config
+}
+
+func NewDomainHandler(
+	repo repository.DomainRepository,
+	policy *urlpolicy.Engine,
+	logger *zap.Logger,
+	cfg *config.Config,
+) *DomainHandler {
+	return &DomainHandler{
+		repo:   repo,
+		policy: policy,
+		logger: logger,
+		cfg:    cfg,
+	}
+}
+
+// List returns domains the workspace can create links on.
+func (h *DomainHandler) List(w http.ResponseWriter, r *http.Request) {
+	workspace := auth.MembershipFromContext(r.Context())
+
+	domains, err := h.repo.ListForWorkspace(r.Context(), workspace.WorkspaceID)
+	if err != nil {
+		h.logger.Error("failed to list domains", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return
+	}
+
+	render.JSON(w, r, domains)
+}
+
+// Create registers a branded domain for the workspace. Ownership of the
+// host is not verified, so only operator keys may call it. DNS for the host
+// must point at the service.
+func (h *DomainHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req dto.DomainRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	hostname := normalizeHost(req.Hostname)
+	for _, host := range h.cfg.SelfHosts() {
+		if hostname == normalizeHost(host) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			render.JSON(w, r, render.M{"errors": render.M{"Hostname": "hostname of the service itself"}})
+			return
+		}
+	}
+
+	for _, dest := range []struct{ field, url string }{
+		{"fallback_url", req.FallbackUrl},
+		{"not_found_url", req.NotFoundUrl},
+	} {
+		if dest.url == "" {
+			continue
+		}
+		if err := h.policy.Check(r.Context(), dest.url); err != nil {
+			if v, ok := urlpolicy.AsViolation(err); ok {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				render.JSON(w, r, render.M{"error": "url rejected", "field": dest.field, "rule": v.Rule, "reason": v.Reason})
+				return
+			}
+			h.logger.Error("url policy check failed", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, render.M{"error": "internal error"})
+			return
+		}
+	}
+
+	workspace := auth.MembershipFromContext(r.Context())
+	domain := &model.Domain{
+		Hostname:    hostname,
+		WorkspaceID: &workspace.WorkspaceID,
+	}
+	if req.FallbackUrl != "" {
+		domain.FallbackUrl = &req.FallbackUrl
+	}
+	if req.NotFoundUrl != "" {
+		domain.NotFoundUrl = &req.NotFoundUrl
+	}
+
+	if err := h.repo.Create(r.Context(), domain); err != nil {
+		if isUniqueViolation(err) {
+			w.WriteHeader(http.StatusConflict)
+			render.JSON(w, r, render.M{"error": "domain already registered"})
+			return
+		}
+		h.logger.Error("failed to create domain", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	render.JSON(w, r, domain)
+}
+
+// workspaceDomain resolves the domain a request refers to by hostname.
+// An empty hostname means the default host and yields a nil domain; ok is
+// false when the hostname is unknown or belongs to another workspace.
+func workspaceDomain(
+	ctx context.Context,
+	repo repository.DomainRepository,
+	workspaceID int64,
+	hostname string,
+) (domain *model.Domain, ok bool, err error) {
+	if hostname == "" {
+		return nil, true, nil
+	}
+
+	domain, err = repo.GetByHostname(ctx, normalizeHost(hostname))
+	if err != nil {
+		return nil, false, err
+	}
+	if domain == nil || !domain.IsUsableBy(workspaceID) {
+		return nil, false, nil
+	}
+
+	return domain, true, nil
+}
+
+func domainID(domain *model.Domain) *int64 {
+	if domain == nil {
+		return nil
+	}
+
+	return &domain.ID
+}
+
+// normalizeHost lowercases the host and drops the port and trailing dot.
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if i := strings.LastIndexByte(host, ':'); i != -1 && !strings.Contains(host[i:], "]") {
+		host = host[:i]
+	}
+
+	return strings.TrimSuffix(host, ".")
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
@@ -0,0 +1,200 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"shorter/internal/auth"
+	"shorter/internal/config"
+	"shorter/internal/export"
+	"shorter/internal/model"
+	"shorter/internal/repository"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultExportAsyncThreshold = 100_000
+	// a download client that cannot take a write in time is dropped
+	exportWriteTimeout = 30 * time.Second
+)
+
+type ExportHandler struct {
+	repo   repository.ExportRepository
+	logger *zap.Logger
+	cfg    *config.Config
+}
+
+func NewExportHandler(repo repository.ExportRepository, logger *zap.Logger, cfg *config.Config) *ExportHandler {
+	return &ExportHandler{
+		repo:   repo,
+		logger: logger,
+		cfg:    cfg,
+	}
+}
+
+// Get returns the state of an export job, with the download URL once the
+// file is ready.
+func (h *ExportHandler) Get(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.getWorkspaceJob(w, r)
+	if !ok {
+		return
+	}
+
+	render.JSON(w, r, job)
+}
+
+// Download serves the file of a finished export job.
+func (h *ExportHandler) Download(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.getWorkspaceJob(w, r)
+	if !ok {
+		return
+	}
+	if job.Status != model.ExportDone || job.FilePath == nil {
+		w.WriteHeader(http.StatusConflict)
+		render.JSON(w, r, render.M{"error": "export is " + job.Status})
+		return
+	}
+
+	f, err := os.Open(*job.FilePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			w.WriteHeader(http.StatusGone)
+			render.JSON(w, r, render.M{"error": "export file is no longer available"})
+			return
+		}
+		h.logger.Error("failed to open export file", zap.Error(err), zap.Int64("job_id", job.ID))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		h.logger.Error("failed to stat export file", zap.Error(err), zap.Int64("job_id", job.ID))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return
+	}
+
+	name := export.FileName(&job.ExportQuery)
+	w.Header().Set("Content-Type", export.ContentType(job.Format))
+	w.Header().Set("Content-Disposition", attachment(name))
+	http.ServeContent(newDeadlineWriter(w, exportWriteTimeout), r, name, info.ModTime(), f)
+}
+
+func (h *ExportHandler) getWorkspaceJob(w http.ResponseWriter, r *http.Request) (*model.ExportJob, bool) {
+	id, ok := urlID(w, r, "exportID", "export id")
+	if !ok {
+		return nil, false
+	}
+
+	workspace := auth.MembershipFromContext(r.Context())
+	job, err := h.repo.Get(r.Context(), workspace.WorkspaceID, id)
+	if err != nil {
+		h.logger.Error("failed to get export job", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return nil, false
+	}
+	if job == nil {
+		w.WriteHeader(http.StatusNotFound)
+		render.JSON(w, r, render.M{"error": "export not found"})
+		return nil, false
+	}
+
+	if job.Status == model.ExportDone {
+		job.DownloadUrl = exportUrl(h.cfg, job.ID) + "/download"
+	}
+
+	return job, true
+}
+
+// parseExportQuery reads format, aggregate, from and to of an export
+// request. Dates without time cover whole days, so to=2024-01-31
+// includes the 31st.
+func parseExportQuery(r *http.Request, link *model.Link) (*model.ExportQuery, error) {
+	params := r.URL.Query()
+	q := &model.ExportQuery{
+		LinkID:    link.ID,
+		Alias:     link.Alias,
+		Format:    params.Get("format"),
+		Aggregate: params.Get("aggregate"),
+	}
+
+	if q.Format == "" {
+		q.Format = model.ExportCSV
+	}
+	if !export.IsFormat(q.Format) {
+		return nil, fmt.Errorf("format must be csv, ndjson or parquet")
+	}
+	if q.Aggregate != "" && q.Aggregate != "hour" && q.Aggregate != "day" {
+		return nil, fmt.Errorf("aggregate must be hour or day")
+	}
+
+	var err error
+	if q.From, err = parseExportTime(params.Get("from"), false); err != nil {
+		return nil, fmt.Errorf("from: %w", err)
+	}
+	if q.To, err = parseExportTime(params.Get("to"), true); err != nil {
+		return nil, fmt.Errorf("to: %w", err)
+	}
+	if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+
+	return q, nil
+}
+
+func parseExportTime(v string, end bool) (*time.Time, error) {
+	if v == "" {
+		return nil, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return nil, fmt.Errorf("expected RFC 3339 time or YYYY-MM-DD date")
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return &t, nil
+}
+
+func exportUrl(cfg *config.Config, id int64) string {
+	return cfg.Server.PublicBaseURL + "/api/v1/exports/" + strconv.FormatInt(id, 10)
+}
+
+func attachment(name string) string {
+	return fmt.Sprintf("attachment; filename=%q", name)
+}
+
+// deadlineWriter moves the write deadline forward on every write, so long
+// downloads outlive the server write timeout while stalled clients do not.
+type deadlineWriter struct {
+	http.ResponseWriter
+	rc      *http.ResponseController
+	timeout time.Duration
+}
+
+func newDeadlineWriter(w http.ResponseWriter, timeout time.Duration) *deadlineWriter {
+	return &deadlineWriter{ResponseWriter: w, rc: http.NewResponseController(w), timeout: timeout}
+}
+
+func (d *deadlineWriter) Write(p []byte) (int, error) {
+	if err := d.rc.SetWriteDeadline(time.Now().Add(d.timeout)); err != nil {
+		return 0, err
+	}
+
+	return d.ResponseWriter.Write(p)
+}
@@ -0,0 +1,302 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"shorter/internal/auth"
+	"shorter/internal/config"
+	"shorter/internal/dto"
+	"shorter/internal/model"
+	"shorter/internal/qr"
+	"shorter/internal/repository"
+	"shorter/internal/request"
+	"shorter/internal/routing"
+	"shorter/internal/webhook"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+)
+
+type LinkHandler struct {
+	repo     repository.LinkRepository
+	domains  repository.DomainRepository
+	health   repository.HealthRepository
+	webhooks *webhook.Dispatcher
+	qr       *qr.Renderer
+	router   *routing.Router
+	logger   *zap.Logger
+	cfg      *config.Config
+}
+
+func NewLinkHandler(
+	repo repository.LinkRepository,
+	domains repository.DomainRepository,
+	health repository.HealthRepository,
+	webhooks *webhook.Dispatcher,
+	qr *qr.Renderer,
+	router *routing.Router,
+	logger *zap.Logger,
+	cfg *config.Config,
+) *LinkHandler {
+	return &LinkHandler{
+		repo:     repo,
+		domains:  domains,
+		health:   health,
+		webhooks: webhooks,
+		qr:       qr,
+		router:   router,
+		logger:   logger,
+		cfg:      cfg,
+	}
+}
+
+func (h *LinkHandler) Get(w http.ResponseWriter, r *http.Request) {
+	link, domain, ok := h.getWorkspaceLink(w, r)
+	if !ok {
+		return
+	}
+
+	health, err := h.health.GetByLinkID(r.Context(), link.ID)
+	if err != nil {
+		h.logger.Error("failed to get link health", zap.Error(err), zap.Int64("link_id", link.ID))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return
+	}
+
+	resp := h.toResponse(link, domain)
+	resp.Health = health
+
+	render.JSON(w, r, resp)
+}
+
+// QR renders a QR code of the public short URL.
+func (h *LinkHandler) QR(w http.ResponseWriter, r *http.Request) {
+	opts, err := qr.ParseOptions(r.URL.Query())
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, render.M{"error": err.Error()})
+		return
+	}
+
+	link, domain, ok := h.getWorkspaceLink(w, r)
+	if !ok {
+		return
+	}
+
+	body, contentType, err := h.qr.Render(shortUrl(h.cfg, domain, link.Alias), opts)
+	if errors.Is(err, qr.ErrNoLogo) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		render.JSON(w, r, render.M{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to render qr code", zap.Error(err), zap.String("alias", link.Alias))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Write(body)
+}
+
+// DryRun shows where a simulated click would be sent, using the same
+// request extraction and routing as real redirects.
+func (h *LinkHandler) DryRun(w http.ResponseWriter, r *http.Request) {
+	var req dto.DryRunRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	link, _, ok := h.getWorkspaceLink(w, r)
+	if !ok {
+		return
+	}
+
+	if req.HasRules() {
+		rules, err := routing.ParseRules(req.Rules)
+		if verr, ok := routing.AsValidationError(err); ok {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			render.JSON(w, r, render.M{"errors": render.M{"rules": verr.Errors}})
+			return
+		}
+		if err != nil {
+			h.logger.Error("failed to parse routing rules", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, render.M{"error": "internal error"})
+			return
+		}
+		link.RoutingRules = rules
+	}
+
+	sim := req.Request
+	query := url.Values{}
+	for k, v := range sim.Query {
+		query.Set(k, v)
+	}
+	// a fresh context, the click must not inherit the caller's address
+	ctx := request.WithClientIP(context.Background(), sim.IP)
+	click, err := http.NewRequestWithContext(ctx, http.MethodGet, "/"+link.Alias+"?"+query.Encode(), nil)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		render.JSON(w, r, render.M{"error": err.Error()})
+		return
+	}
+	click.Header.Set("User-Agent", sim.UserAgent)
+	click.Header.Set("Accept-Language", sim.AcceptLanguage)
+	click.Header.Set("Referer", sim.Referer)
+
+	now := time.Now()
+	if sim.Time != nil {
+		now = *sim.Time
+	}
+	routingReq := h.router.Request(click, now)
+	if sim.Country != "" {
+		routingReq.Country = sim.Country
+	}
+
+	decision := routing.Route(link, routingReq)
+	resp := render.M{"request": routingReq, "decision": decision}
+	// variants are drawn per click, list the candidates
+	if decision.Source == routing.SourceDefault && len(link.Variants) > 0 {
+		resp["variants"] = link.Variants
+	}
+
+	render.JSON(w, r, resp)
+}
+
+func (h *LinkHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	h.setActive(w, r, false)
+}
+
+func (h *LinkHandler) Enable(w http.ResponseWriter, r *http.Request) {
+	h.setActive(w, r, true)
+}
+
+func (h *LinkHandler) setActive(w http.ResponseWriter, r *http.Request, active bool) {
+	link, domain, ok := h.getWorkspaceLink(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.LinkStateRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	key := auth.KeyFromContext(r.Context())
+	changedBy := fmt.Sprintf("api_key:%d", key.ID)
+
+	if _, err := h.repo.SetActive(r.Context(), *link.WorkspaceID, link, active, changedBy, req.Reason); err != nil {
+		h.logger.Error("failed to change link state", zap.Error(err), zap.String("alias", link.Alias))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return
+	}
+
+	h.logger.Info("link state changed",
+		zap.String("alias", link.Alias),
+		zap.Bool("is_active", active),
+		zap.String("changed_by", changedBy),
+		zap.String("reason", req.Reason),
+	)
+
+	link.IsActive = active
+
+	data := webhook.NewLinkData(link, domain, time.Now())
+	data.ChangedBy, data.Reason = changedBy, req.Reason
+	if err := h.webhooks.Emit(r.Context(), *link.WorkspaceID, model.WebhookLinkUpdated, data); err != nil {
+		h.logger.Error("failed to queue link.updated", zap.Error(err), zap.String("alias", link.Alias))
+	}
+
+	render.JSON(w, r, h.toResponse(link, domain))
+}
+
+// getWorkspaceLink loads the link from the URL within the workspace of the
+// request, on the domain given by the ?domain= query param. Links of other
+// workspaces are reported as not found.
+func (h *LinkHandler) getWorkspaceLink(w http.ResponseWriter, r *http.Request) (*model.Link, *model.Domain, bool) {
+	alias := chi.URLParam(r, "alias")
+	if alias == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, render.M{"error": "alias is required"})
+		return nil, nil, false
+	}
+
+	workspace := auth.MembershipFromContext(r.Context())
+
+	domain, ok, err := workspaceDomain(r.Context(), h.domains, workspace.WorkspaceID, r.URL.Query().Get("domain"))
+	if err != nil {
+		h.logger.Error("failed to get domain", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return nil, nil, false
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		render.JSON(w, r, render.M{"error": "link not found"})
+		return nil, nil, false
+	}
+
+	link, err := h.repo.GetByAliasInWorkspace(r.Context(), workspace.WorkspaceID, domainID(domain), alias)
+	if err != nil {
+		h.logger.Error("failed to get link by alias", zap.Error(err), zap.String("alias", alias))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return nil, nil, false
+	}
+	if link == nil {
+		w.WriteHeader(http.StatusNotFound)
+		render.JSON(w, r, render.M{"error": "link not found"})
+		return nil, nil, false
+	}
+
+	return link, domain, true
+}
+
+func (h *LinkHandler) toResponse(link *model.Link, domain *model.Domain) dto.LinkResponse {
+	resp := dto.LinkResponse{
+		Alias:       link.Alias,
+		ShortUrl:    shortUrl(h.cfg, domain, link.Alias),
+		OriginalUrl: link.OriginalUrl,
+		Status:      string(link.Status(time.Now())),
+		CreatedAt:   link.CreatedAt,
+		ActiveFrom:  link.ActiveFrom,
+		ExpiresAt:   link.ExpiresAt,
+		ClickCount:  link.ClickCount,
+		MaxClicks:   link.MaxClicks,
+		Protected:   link.IsProtected(),
+		WorkspaceID: link.WorkspaceID,
+	}
+	if domain != nil {
+		resp.Domain = domain.Hostname
+	}
+	for _, rule := range link.GeoRules {
+		resp.GeoRules = append(resp.GeoRules, dto.GeoRule{Countries: rule.Countries, Url: rule.Url})
+	}
+	for _, rule := range link.DeviceRules {
+		resp.DeviceRules = append(resp.DeviceRules, dto.DeviceRule{Platforms: rule.Platforms, Url: rule.Url, AppUrl: rule.AppUrl})
+	}
+	for _, v := range link.Variants {
+		resp.Variants = append(resp.Variants, dto.Variant{Name: v.Name, Url: v.Url, Weight: v.Weight})
+	}
+	resp.Sticky = link.StickyVariants
+	resp.RoutingRules = link.RoutingRules
+	resp.Preview = link.Preview
+	for _, p := range link.Pixels {
+		resp.Pixels = append(resp.Pixels, dto.Pixel{Type: p.Type, Url: p.Url, Snippet: p.Snippet})
+	}
+	resp.PixelDelayMs = link.PixelDelayMs
+	if link.Meta != nil {
+		resp.Meta = &dto.LinkMeta{Title: link.Meta.Title, Description: link.Meta.Description, Image: link.Meta.Image}
+	}
+
+	return resp
+}
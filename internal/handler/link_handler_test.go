@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"shorter/internal/auth"
+	"shorter/internal/enricher"
+	"shorter/internal/geo"
+	"shorter/internal/model"
+	"shorter/internal/repository"
+	"shorter/internal/request"
+	"shorter/internal/routing"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/oschwald/geoip2-golang"
+	"go.uber.org/zap"
+)
+
+// linkRepositoryStub serves a single link, other methods are not used.
+type linkRepositoryStub struct {
+	repository.LinkRepository
+	link *model.Link
+}
+
+func (r *linkRepositoryStub) GetByAliasInWorkspace(_ context.Context, _ int64, _ *int64, alias string) (*model.Link, error) {
+	if alias != r.link.Alias {
+		return nil, nil
+	}
+	link := *r.link
+
+	return &link, nil
+}
+
+// countryTable maps addresses to countries.
+type countryTable map[string]string
+
+func (t countryTable) Country(ip net.IP) (*geoip2.Country, error) {
+	code, ok := t[ip.String()]
+	if !ok {
+		return nil, errors.New("address not found")
+	}
+	var country geoip2.Country
+	country.Country.IsoCode = code
+
+	return &country, nil
+}
+
+func TestDryRunUsesSimulatedIP(t *testing.T) {
+	workspaceID := int64(1)
+	repo := &linkRepositoryStub{link: &model.Link{ID: 1, Alias: "promo", OriginalUrl: "https://example.com", WorkspaceID: &workspaceID}}
+	locator := geo.NewReaderLocator(countryTable{"198.51.100.7": "DE", "203.0.113.9": "US"}, "")
+	h := NewLinkHandler(repo, nil, nil, nil, nil, routing.NewRouter(locator, enricher.NewDeviceParser()), zap.NewNop(), nil)
+
+	// the API caller itself is in the US
+	body := `{"request": {"ip": "198.51.100.7"}}`
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/links/promo/dry-run", strings.NewReader(body))
+	r.RemoteAddr = "203.0.113.9:40000"
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("alias", "promo")
+	ctx := context.WithValue(r.Context(), chi.RouteCtxKey, routeCtx)
+	ctx = auth.WithMembership(ctx, &model.Membership{WorkspaceID: workspaceID, Role: model.RoleViewer})
+
+	w := httptest.NewRecorder()
+	request.TrustProxies(nil)(http.HandlerFunc(h.DryRun)).ServeHTTP(w, r.WithContext(ctx))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+
+	var resp struct {
+		Request routing.Request `json:"request"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Request.Country != "DE" {
+		t.Errorf("country = %q, want DE of the simulated ip", resp.Request.Country)
+	}
+}
@@ -2,77 +2,160 @@ package handler
 
 import (
 	"context"
+	"fmt"
+	"html/template"
+	"math"
+	"math/rand/v2"
 	"net/http"
+	"shorter/internal/config"
 	"shorter/internal/events"
 	"shorter/internal/metrics"
+	"shorter/internal/model"
+	"shorter/internal/pages"
 	"shorter/internal/producer"
 	"shorter/internal/repository"
+	"shorter/internal/request"
+	"shorter/internal/routing"
+	"shorter/internal/security"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 )
 
+const (
+	unlockCookiePrefix = "shorter_unlock_"
+	unlockTTL          = 12 * time.Hour
+
+	variantCookiePrefix = "shorter_variant_"
+	variantTTL          = 30 * 24 * time.Hour
+
+	previewSuffix = "+"
+	continueParam = "continue"
+)
+
 type RedirectHandler struct {
 	repo     repository.LinkRepository
+	domains  repository.DomainRepository
 	producer *producer.KafkaProducer
+	router   *routing.Router
+	pages    *pages.Renderer
+	signer   *security.Signer
+	limiter  *security.AttemptLimiter
 	logger   *zap.Logger
+	cfg      *config.Config
 }
 
 func NewRedirectHandler(
 	repo repository.LinkRepository,
+	domains repository.DomainRepository,
 	producer *producer.KafkaProducer,
+	router *routing.Router,
+	pages *pages.Renderer,
+	signer *security.Signer,
+	limiter *security.AttemptLimiter,
 	logger *zap.Logger,
+	cfg *config.Config,
 ) *RedirectHandler {
 	return &RedirectHandler{
 		repo:     repo,
+		domains:  domains,
 		producer: producer,
+		router:   router,
+		pages:    pages,
+		signer:   signer,
+		limiter:  limiter,
 		logger:   logger,
+		cfg:      cfg,
 	}
 }
 
 func (rh *RedirectHandler) Handle(w http.ResponseWriter, r *http.Request) {
-	alias := chi.URLParam(r, "alias")
-	if alias == "" {
-		metrics.RedirectsErrorTotal.Inc()
-		http.Error(w, "alias is required", http.StatusBadRequest)
+	link, domain, ok := rh.getLink(w, r)
+	if !ok {
 		return
 	}
 
-	link, err := rh.repo.GetByAlias(r.Context(), alias)
-	if err != nil {
-		metrics.RedirectsErrorTotal.Inc()
-		rh.logger.Error("db error", zap.Error(err))
-		http.Error(w, "internal error", http.StatusInternalServerError)
+	switch link.Status(time.Now()) {
+	case model.LinkStatusDisabled:
+		rh.unavailable(w, r, domain)
+		return
+	case model.LinkStatusScheduled:
+		rh.scheduled(w, r, link, domain)
+		return
+	case model.LinkStatusExpired:
+		rh.expired(w, r, link, domain)
 		return
 	}
-	if link == nil {
-		metrics.RedirectsErrorTotal.Inc()
-		http.Error(w, "internal error", http.StatusNotFound)
+
+	// protected link: ask for password until unlocked
+	if link.IsProtected() && !rh.isUnlocked(r, link) {
+		rh.renderPasswordForm(w, http.StatusUnauthorized, "")
 		return
 	}
 
-	// update ckicks count
-	if err := rh.repo.IncClickCount(r.Context(), alias); err != nil {
+	if link.IsExhausted() {
+		rh.gone(w, r, domain)
+		return
+	}
+
+	alias := link.Alias
+
+	// bots unfurling a shared link get the card, they are not clicks and
+	// must not use up max_clicks of one-time links
+	if request.IsCrawler(r) {
+		rh.unfurl(w, r, link, domain)
+		return
+	}
+
+	// "/{alias}+" or a preview link shows where it leads before going there
+	preview := strings.HasSuffix(chi.URLParam(r, "alias"), previewSuffix) || link.Preview
+	if preview && !r.URL.Query().Has(continueParam) {
+		rh.preview(w, r, link, domain)
+		return
+	}
+
+	// update ckicks count, limited links must not redirect past max_clicks
+	clicks, counted, err := rh.repo.IncClickCount(r.Context(), link.ID)
+	if err != nil {
 		rh.logger.Error("failed to increment click count", zap.Error(err))
+		if link.MaxClicks != nil {
+			metrics.RedirectsErrorTotal.Inc()
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+	} else if !counted {
+		rh.gone(w, r, domain)
+		return
 	}
 
 	metrics.RedirectsTotal.WithLabelValues(alias).Inc()
 
-	ip := r.Header.Get("X-Forwarded-For")
-	if ip == "" {
-		ip = r.RemoteAddr
+	target := rh.destination(r, link)
+	if target.variant != "" && link.StickyVariants {
+		http.SetCookie(w, &http.Cookie{
+			Name:     variantCookiePrefix + alias,
+			Value:    target.variant,
+			Path:     publicPath(rh.cfg, domain, alias),
+			MaxAge:   int(variantTTL.Seconds()),
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
 	}
-	userAgent := r.Header.Get("User-Agent")
-	referer := r.Header.Get("Referer")
 
 	// create event
 	event := &events.ClickEvent{
-		Alias:     alias,
-		Timestamp: time.Now().UTC(),
-		IP:        ip,
-		UserAgent: userAgent,
-		Referer:   referer,
+		LinkID:      link.ID,
+		WorkspaceID: link.WorkspaceID,
+		Alias:       alias,
+		Timestamp:   time.Now().UTC(),
+		IP:          request.ClientIP(r),
+		UserAgent:   r.Header.Get("User-Agent"),
+		Referer:     r.Header.Get("Referer"),
+		Variant:     target.variant,
+		ClickCount:  clicks,
 	}
 
 	// send event to kafka
@@ -85,5 +168,313 @@ func (rh *RedirectHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		}
 	}()
 
-	http.Redirect(w, r, link.OriginalUrl, http.StatusFound)
+	if target.appUrl != "" {
+		rh.openApp(w, target.appUrl, target.url)
+		return
+	}
+
+	if len(link.Pixels) > 0 {
+		rh.firePixels(w, link, target.url)
+		return
+	}
+
+	http.Redirect(w, r, target.url, http.StatusFound)
+}
+
+// target is where a click goes. With appUrl set the app is tried before
+// url, variant names the A/B variant that was picked.
+type target struct {
+	url     string
+	appUrl  string
+	variant string
+}
+
+// destination picks where the visitor goes: targeting rules first, then
+// the weighted variants and the link's own URL by default.
+func (rh *RedirectHandler) destination(r *http.Request, link *model.Link) target {
+	// geo and user agent lookups only for links that need them
+	if link.HasTargeting() {
+		decision := routing.Route(link, rh.router.Request(r, time.Now()))
+		if decision.Source != routing.SourceDefault {
+			return target{url: decision.Url, appUrl: decision.AppUrl}
+		}
+	}
+
+	if len(link.Variants) > 0 {
+		if v := rh.variant(r, link); v != nil {
+			return target{url: v.Url, variant: v.Name}
+		}
+	}
+
+	return target{url: link.OriginalUrl}
+}
+
+// variant keeps returning visitors on the variant they saw first when the
+// link is sticky, otherwise draws a new one per click.
+func (rh *RedirectHandler) variant(r *http.Request, link *model.Link) *model.Variant {
+	if link.StickyVariants {
+		if cookie, err := r.Cookie(variantCookiePrefix + link.Alias); err == nil {
+			if v := link.VariantByName(cookie.Value); v != nil {
+				return v
+			}
+		}
+	}
+
+	return link.PickVariant(rand.IntN)
+}
+
+// preview renders the interstitial with the destination and a safety
+// notice. Continue leads back to the link, which then redirects.
+func (rh *RedirectHandler) preview(w http.ResponseWriter, r *http.Request, link *model.Link, domain *model.Domain) {
+	query := r.URL.Query()
+	query.Set(continueParam, "1")
+
+	data := struct {
+		model.LinkMeta
+		Url         string
+		ContinueUrl string
+	}{
+		Url:         rh.destination(r, link).url,
+		ContinueUrl: publicPath(rh.cfg, domain, link.Alias) + "?" + query.Encode(),
+	}
+	if link.Meta != nil {
+		data.LinkMeta = *link.Meta
+	}
+
+	if err := rh.pages.Render(w, http.StatusOK, "preview", data); err != nil {
+		rh.logger.Error("failed to render preview page", zap.Error(err))
+	}
+}
+
+// unfurl serves the link's OpenGraph and Twitter card to preview bots, a
+// bare one for links without meta.
+func (rh *RedirectHandler) unfurl(w http.ResponseWriter, r *http.Request, link *model.Link, domain *model.Domain) {
+	data := struct {
+		model.LinkMeta
+		Url      string
+		ShortUrl string
+	}{
+		Url:      link.OriginalUrl,
+		ShortUrl: shortUrl(rh.cfg, domain, link.Alias),
+	}
+	if link.Meta != nil {
+		data.LinkMeta = *link.Meta
+	}
+
+	if err := rh.pages.Render(w, http.StatusOK, "unfurl", data); err != nil {
+		rh.logger.Error("failed to render unfurl page", zap.Error(err))
+	}
+}
+
+// firePixels renders the page that loads the link's tracking pixels and
+// forwards to the destination after the delay.
+func (rh *RedirectHandler) firePixels(w http.ResponseWriter, link *model.Link, destination string) {
+	type pixel struct {
+		Type    string
+		Url     string
+		Snippet template.HTML
+	}
+
+	delay := rh.cfg.Pixels.RedirectDelay.Milliseconds()
+	if link.PixelDelayMs != nil {
+		delay = int64(*link.PixelDelayMs)
+	}
+
+	data := struct {
+		Pixels  []pixel
+		Url     string
+		DelayMs int64
+	}{Url: destination, DelayMs: delay}
+	for _, p := range link.Pixels {
+		// snippets are only accepted when allow_snippets is on
+		data.Pixels = append(data.Pixels, pixel{Type: p.Type, Url: p.Url, Snippet: template.HTML(p.Snippet)})
+	}
+
+	if err := rh.pages.Render(w, http.StatusOK, "pixels", data); err != nil {
+		rh.logger.Error("failed to render pixels page", zap.Error(err))
+	}
+}
+
+// openApp renders the page that launches the app and falls back to the
+// store or web URL when nothing handles the app URI.
+func (rh *RedirectHandler) openApp(w http.ResponseWriter, appUrl, fallbackUrl string) {
+	data := struct {
+		// validated on creation, html/template would drop custom schemes
+		AppUrl      template.URL
+		FallbackUrl string
+	}{AppUrl: template.URL(appUrl), FallbackUrl: fallbackUrl}
+
+	if err := rh.pages.Render(w, http.StatusOK, "app", data); err != nil {
+		rh.logger.Error("failed to render app page", zap.Error(err))
+	}
+}
+
+// Root answers requests to the bare short domain with the domain fallback.
+func (rh *RedirectHandler) Root(w http.ResponseWriter, r *http.Request) {
+	domain, err := rh.getDomain(r)
+	if err != nil {
+		rh.logger.Error("db error", zap.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	rh.fallback(w, r, http.StatusNotFound, "not_found", nil, domainFallback(domain))
+}
+
+// Unlock verifies the password submitted for a protected link. On success it
+// sets a signed session cookie and sends the visitor back to the link.
+func (rh *RedirectHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	link, domain, ok := rh.getLink(w, r)
+	if !ok {
+		return
+	}
+
+	linkPath := publicPath(rh.cfg, domain, link.Alias)
+	if !link.IsProtected() {
+		http.Redirect(w, r, linkPath, http.StatusSeeOther)
+		return
+	}
+
+	key := request.ClientIP(r) + "|" + link.Alias
+	if allowed, wait := rh.limiter.Allow(key); !allowed {
+		metrics.UnlockFailuresTotal.Inc()
+		w.Header().Set("Retry-After", fmt.Sprint(int(math.Ceil(wait.Seconds()))))
+		rh.renderPasswordForm(w, http.StatusTooManyRequests, "Too many attempts, try again later")
+		return
+	}
+
+	if !security.CheckPassword(*link.PasswordHash, r.PostFormValue("password")) {
+		metrics.UnlockFailuresTotal.Inc()
+		rh.renderPasswordForm(w, http.StatusUnauthorized, "Wrong password")
+		return
+	}
+	rh.limiter.Reset(key)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     unlockCookiePrefix + link.Alias,
+		Value:    rh.signer.Sign(unlockPayload(link), unlockTTL),
+		Path:     linkPath,
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, linkPath, http.StatusSeeOther)
+}
+
+// getLink resolves the link by the Host header and the alias. Unknown hosts
+// are served as the default domain.
+func (rh *RedirectHandler) getLink(w http.ResponseWriter, r *http.Request) (*model.Link, *model.Domain, bool) {
+	alias := strings.TrimSuffix(chi.URLParam(r, "alias"), previewSuffix)
+	if alias == "" {
+		metrics.RedirectsErrorTotal.Inc()
+		http.Error(w, "alias is required", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	domain, err := rh.getDomain(r)
+	if err != nil {
+		metrics.RedirectsErrorTotal.Inc()
+		rh.logger.Error("db error", zap.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	link, err := rh.repo.GetByAlias(r.Context(), domainID(domain), alias)
+	if err != nil {
+		metrics.RedirectsErrorTotal.Inc()
+		rh.logger.Error("db error", zap.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if link == nil {
+		metrics.RedirectsErrorTotal.Inc()
+		var notFoundUrl *string
+		if domain != nil {
+			notFoundUrl = domain.NotFoundUrl
+		}
+		rh.fallback(w, r, http.StatusNotFound, "not_found", nil, notFoundUrl)
+		return nil, nil, false
+	}
+
+	return link, domain, true
+}
+
+func (rh *RedirectHandler) getDomain(r *http.Request) (*model.Domain, error) {
+	return rh.domains.GetByHostname(r.Context(), normalizeHost(r.Host))
+}
+
+// gone answers for links that used up max_clicks.
+func (rh *RedirectHandler) gone(w http.ResponseWriter, r *http.Request, domain *model.Domain) {
+	metrics.RedirectsErrorTotal.Inc()
+
+	exhaustedUrl := &rh.cfg.Links.ExhaustedRedirectURL
+	rh.fallback(w, r, http.StatusGone, "gone", nil, exhaustedUrl, domainFallback(domain))
+}
+
+// unavailable answers for links switched off by an operator.
+func (rh *RedirectHandler) unavailable(w http.ResponseWriter, r *http.Request, domain *model.Domain) {
+	metrics.RedirectsErrorTotal.Inc()
+
+	rh.fallback(w, r, http.StatusGone, "unavailable", nil, domainFallback(domain))
+}
+
+// scheduled answers for links whose active_from is still ahead with a
+// "coming soon" page unless a fallback URL is set.
+func (rh *RedirectHandler) scheduled(w http.ResponseWriter, r *http.Request, link *model.Link, domain *model.Domain) {
+	data := struct{ ActiveFrom *time.Time }{ActiveFrom: link.ActiveFrom}
+	rh.fallback(w, r, http.StatusNotFound, "scheduled", data, link.ScheduledRedirectUrl, domainFallback(domain))
+}
+
+// expired answers for links past expires_at with 410 Gone unless a
+// fallback URL is set.
+func (rh *RedirectHandler) expired(w http.ResponseWriter, r *http.Request, link *model.Link, domain *model.Domain) {
+	metrics.RedirectsErrorTotal.Inc()
+
+	rh.fallback(w, r, http.StatusGone, "expired", nil, link.ExpiredRedirectUrl, domainFallback(domain))
+}
+
+// fallback redirects to the first non-empty URL, most specific first, and
+// renders the page when none is configured.
+func (rh *RedirectHandler) fallback(w http.ResponseWriter, r *http.Request, status int, page string, data any, urls ...*string) {
+	for _, u := range urls {
+		if u != nil && *u != "" {
+			http.Redirect(w, r, *u, http.StatusFound)
+			return
+		}
+	}
+
+	if err := rh.pages.Render(w, status, page, data); err != nil {
+		rh.logger.Error("failed to render page", zap.Error(err), zap.String("page", page))
+	}
+}
+
+func domainFallback(domain *model.Domain) *string {
+	if domain == nil {
+		return nil
+	}
+
+	return domain.FallbackUrl
+}
+
+func (rh *RedirectHandler) isUnlocked(r *http.Request, link *model.Link) bool {
+	cookie, err := r.Cookie(unlockCookiePrefix + link.Alias)
+	if err != nil {
+		return false
+	}
+
+	return rh.signer.Verify(unlockPayload(link), cookie.Value)
+}
+
+func (rh *RedirectHandler) renderPasswordForm(w http.ResponseWriter, status int, errMsg string) {
+	data := struct{ Error string }{Error: errMsg}
+	if err := rh.pages.Render(w, status, "password", data); err != nil {
+		rh.logger.Error("failed to render password page", zap.Error(err))
+	}
+}
+
+// unlockPayload binds the cookie to the current password hash, so changing
+// the password invalidates previously issued cookies.
+func unlockPayload(link *model.Link) string {
+	return link.Alias + "|" + *link.PasswordHash
 }
@@ -1,15 +1,25 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
+	"shorter/internal/alias"
+	"shorter/internal/auth"
 	"shorter/internal/config"
 	"shorter/internal/dto"
+	"shorter/internal/metrics"
 	"shorter/internal/model"
 	"shorter/internal/repository"
-	"shorter/internal/utils"
+	"shorter/internal/routing"
+	"shorter/internal/security"
+	"shorter/internal/urlnorm"
+	"shorter/internal/urlpolicy"
+	"shorter/internal/webhook"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/render"
@@ -17,21 +27,44 @@ import (
 	"go.uber.org/zap"
 )
 
+const (
+	defaultAliasAttempts  = 10
+	defaultAliasGrowAfter = 3
+)
+
 type ShoterHandler struct {
-	repo   repository.LinkRepository
-	logger *zap.Logger
-	cfg    *config.Config
+	repo        repository.LinkRepository
+	domains     repository.DomainRepository
+	aliases     alias.Generator
+	aliasPolicy *alias.Policy
+	policy      *urlpolicy.Engine
+	webhooks    *webhook.Dispatcher
+	logger      *zap.Logger
+	cfg         *config.Config
+
+	// generated aliases that collided in a row, across requests
+	collisions atomic.Int64
 }
 
 func NewShorterHandler(
 	repo repository.LinkRepository,
+	domains repository.DomainRepository,
+	aliases alias.Generator,
+	aliasPolicy *alias.Policy,
+	policy *urlpolicy.Engine,
+	webhooks *webhook.Dispatcher,
 	logger *zap.Logger,
 	cfg *config.Config,
 ) *ShoterHandler {
 	return &ShoterHandler{
-		repo:   repo,
-		logger: logger,
-		cfg:    cfg,
+		repo:        repo,
+		domains:     domains,
+		aliases:     aliases,
+		aliasPolicy: aliasPolicy,
+		policy:      policy,
+		webhooks:    webhooks,
+		logger:      logger,
+		cfg:         cfg,
 	}
 }
 
@@ -47,11 +80,6 @@ func (s *ShoterHandler) Handle(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// validation
-	var validate = validator.New()
-	validate.RegisterValidation("url", func(fl validator.FieldLevel) bool {
-		_, err := url.ParseRequestURI(fl.Field().String())
-		return err == nil
-	})
 	if err := validate.Struct(req); err != nil {
 		errs := make(map[string]string)
 		for _, e := range err.(validator.ValidationErrors) {
@@ -61,38 +89,178 @@ func (s *ShoterHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		render.JSON(w, r, render.M{"errors": errs})
 		return
 	}
+	// max in the tag counts runes, bcrypt counts bytes
+	if len(req.Password) > security.MaxPasswordBytes {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		render.JSON(w, r, render.M{"errors": render.M{"password": fmt.Sprintf("password must be at most %d bytes", security.MaxPasswordBytes)}})
+		return
+	}
 
-	// generate alias
-	alias := req.CustomAlias
-	if alias == "" {
-		randomAlias, err := utils.GenerateRandomAlias(6)
+	// reserved and offensive aliases
+	if req.CustomAlias != "" {
+		if err := s.aliasPolicy.Check(req.CustomAlias); err != nil {
+			rejection, _ := alias.AsRejection(err)
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			render.JSON(w, r, render.M{"error": "alias rejected", "field": "custom_alias", "rule": rejection.Rule, "reason": rejection.Reason})
+			return
+		}
+	}
+
+	// destination safety
+	destinations := []struct{ field, url string }{
+		{"original_url", req.OriginalUrl},
+		{"expired_redirect_url", req.ExpiredRedirectUrl},
+		{"scheduled_redirect_url", req.ScheduledRedirectUrl},
+	}
+	for i, rule := range req.GeoRules {
+		destinations = append(destinations, struct{ field, url string }{fmt.Sprintf("geo_rules[%d].url", i), rule.Url})
+	}
+	var routingRules []model.RoutingRule
+	if req.HasRoutingRules() {
+		rules, err := routing.ParseRules(req.RoutingRules)
+		if verr, ok := routing.AsValidationError(err); ok {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			render.JSON(w, r, render.M{"errors": render.M{"routing_rules": verr.Errors}})
+			return
+		}
 		if err != nil {
-			s.logger.Fatal("failed to generate alias", zap.Error(err))
+			s.logger.Error("failed to parse routing rules", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, render.M{"error": "internal error"})
+			return
+		}
+		routingRules = rules
+	}
+	for i, rule := range routingRules {
+		destinations = append(destinations, struct{ field, url string }{fmt.Sprintf("routing_rules[%d].url", i), rule.Url})
+	}
+	for i, p := range req.Pixels {
+		field := fmt.Sprintf("pixels[%d]", i)
+		switch {
+		case p.Type == model.PixelSnippet && !s.cfg.Pixels.AllowSnippets:
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			render.JSON(w, r, render.M{"errors": render.M{field: "html snippets are disabled"}})
+			return
+		case p.Type == model.PixelSnippet && p.Snippet == "":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			render.JSON(w, r, render.M{"errors": render.M{field + ".snippet": "snippet is required"}})
+			return
+		case p.Type != model.PixelSnippet && p.Url == "":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			render.JSON(w, r, render.M{"errors": render.M{field + ".url": "url is required"}})
+			return
+		case p.Url != "":
+			destinations = append(destinations, struct{ field, url string }{field + ".url", p.Url})
+		}
+	}
+	for i, v := range req.Variants {
+		destinations = append(destinations, struct{ field, url string }{fmt.Sprintf("variants[%d].url", i), v.Url})
+	}
+	// not a destination, but bots fetch it from the unfurl card
+	if req.Meta != nil {
+		destinations = append(destinations, struct{ field, url string }{"meta.image", req.Meta.Image})
+	}
+	for i, rule := range req.DeviceRules {
+		destinations = append(destinations, struct{ field, url string }{fmt.Sprintf("device_rules[%d].url", i), rule.Url})
+
+		// app URIs use their own schemes, only web ones go through the policy
+		field := fmt.Sprintf("device_rules[%d].app_url", i)
+		switch scheme := urlScheme(rule.AppUrl); {
+		case rule.AppUrl == "":
+		case scheme == "http" || scheme == "https":
+			destinations = append(destinations, struct{ field, url string }{field, rule.AppUrl})
+		case !isAppScheme(scheme):
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			render.JSON(w, r, render.M{"error": "url rejected", "field": field, "rule": "scheme", "reason": "app_url must use http, https or an app scheme"})
+			return
+		}
+	}
+	for _, dest := range destinations {
+		if dest.url == "" {
+			continue
+		}
+		if err := s.policy.Check(r.Context(), dest.url); err != nil {
+			if v, ok := urlpolicy.AsViolation(err); ok {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				render.JSON(w, r, render.M{"error": "url rejected", "field": dest.field, "rule": v.Rule, "reason": v.Reason})
+				return
+			}
+			s.logger.Error("url policy check failed", zap.Error(err))
 			w.WriteHeader(http.StatusInternalServerError)
 			render.JSON(w, r, render.M{"error": "internal error"})
 			return
 		}
-		alias = randomAlias
 	}
 
-	// check alias already exists
-	linkExist, err := s.repo.GetByAlias(r.Context(), alias)
+	// pick domain
+	workspace := auth.MembershipFromContext(r.Context())
+	domain, ok, err := workspaceDomain(r.Context(), s.domains, workspace.WorkspaceID, req.Domain)
 	if err != nil {
-		s.logger.Fatal("failed to get link by alias", zap.Error(err))
+		s.logger.Error("failed to get domain", zap.Error(err))
 		w.WriteHeader(http.StatusInternalServerError)
 		render.JSON(w, r, render.M{"error": "internal error"})
 		return
 	}
-	if linkExist != nil {
-		w.WriteHeader(http.StatusConflict)
-		render.JSON(w, r, render.M{"error": "alias already in use"})
+	if !ok {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		render.JSON(w, r, render.M{"errors": render.M{"domain": "unknown domain"}})
+		return
+	}
+
+	urlHash, err := urlnorm.Hash(req.OriginalUrl)
+	if err != nil {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		render.JSON(w, r, render.M{"errors": render.M{"original_url": err.Error()}})
 		return
 	}
 
+	// reuse the link the owner already made for this url
+	key := auth.KeyFromContext(r.Context())
+	if req.ReuseExisting && req.IsPlain() && key != nil {
+		existing, err := s.repo.FindReusable(r.Context(), key.ID, workspace.WorkspaceID, domainID(domain), urlHash)
+		if err != nil {
+			s.logger.Error("failed to find reusable link", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, render.M{"error": "internal error"})
+			return
+		}
+		if existing != nil {
+			s.writeResponse(w, existing, domain, http.StatusOK, true)
+			return
+		}
+	}
+
 	// prepare model
 	link := &model.Link{
-		Alias:       alias,
+		Alias:       req.CustomAlias,
 		OriginalUrl: req.OriginalUrl,
+		MaxClicks:   req.MaxClicks,
+		IsActive:    true,
+		WorkspaceID: &workspace.WorkspaceID,
+		DomainID:    domainID(domain),
+		UrlHash:     &urlHash,
+	}
+	for _, rule := range req.GeoRules {
+		link.GeoRules = append(link.GeoRules, model.GeoRule{Countries: rule.Countries, Url: rule.Url})
+	}
+	for _, v := range req.Variants {
+		link.Variants = append(link.Variants, model.Variant{Name: v.Name, Url: v.Url, Weight: v.Weight})
+	}
+	link.StickyVariants = req.StickyVariants
+	link.RoutingRules = routingRules
+	link.Preview = req.Preview
+	for _, p := range req.Pixels {
+		link.Pixels = append(link.Pixels, model.Pixel{Type: p.Type, Url: p.Url, Snippet: p.Snippet})
+	}
+	link.PixelDelayMs = req.PixelDelayMs
+	if req.Meta != nil {
+		link.Meta = &model.LinkMeta{Title: req.Meta.Title, Description: req.Meta.Description, Image: req.Meta.Image}
+	}
+	for _, rule := range req.DeviceRules {
+		link.DeviceRules = append(link.DeviceRules, model.DeviceRule{Platforms: rule.Platforms, Url: rule.Url, AppUrl: rule.AppUrl})
+	}
+	if key != nil {
+		link.OwnerID = &key.ID
 	}
 
 	if req.ExpiresIn != nil {
@@ -100,25 +268,153 @@ func (s *ShoterHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		exp := time.Now().Add(duration)
 		link.ExpiresAt = &exp
 	}
+	if req.ExpiredRedirectUrl != "" {
+		link.ExpiredRedirectUrl = &req.ExpiredRedirectUrl
+	}
 
-	// save link
-	if err := s.repo.Create(r.Context(), link); err != nil {
+	if req.ActiveFrom != nil {
+		activeFrom := req.ActiveFrom.UTC()
+		link.ActiveFrom = &activeFrom
+	}
+	if req.ScheduledRedirectUrl != "" {
+		link.ScheduledRedirectUrl = &req.ScheduledRedirectUrl
+	}
+	if link.ActiveFrom != nil && link.ExpiresAt != nil && !link.ExpiresAt.After(*link.ActiveFrom) {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		render.JSON(w, r, render.M{"errors": render.M{"expires_in": "link must expire after active_from"}})
+		return
+	}
+
+	if req.Password != "" {
+		hash, err := security.HashPassword(req.Password)
+		if err != nil {
+			s.logger.Error("failed to hash password", zap.Error(err))
+			w.WriteHeader(http.StatusInternalServerError)
+			render.JSON(w, r, render.M{"error": "internal error"})
+			return
+		}
+		link.PasswordHash = &hash
+	}
+
+	// save link, the unique index is the only source of truth for taken aliases
+	if link.Alias != "" {
+		err = s.repo.Create(r.Context(), link)
+	} else {
+		err = s.createWithGeneratedAlias(r.Context(), link)
+	}
+	if err != nil {
+		if isUniqueViolation(err) {
+			w.WriteHeader(http.StatusConflict)
+			render.JSON(w, r, render.M{"error": "alias already in use"})
+			return
+		}
 		s.logger.Error("failed to create link", zap.Error(err))
 		w.WriteHeader(http.StatusInternalServerError)
 		render.JSON(w, r, render.M{"error": "internal error"})
 		return
 	}
 
-	// response
-	baseUrl := s.cfg.Server.Host + ":" + fmt.Sprint(s.cfg.Server.Port)
+	if err := s.webhooks.Emit(r.Context(), workspace.WorkspaceID, model.WebhookLinkCreated, webhook.NewLinkData(link, domain, time.Now())); err != nil {
+		s.logger.Error("failed to queue link.created", zap.Error(err), zap.String("alias", link.Alias))
+	}
+
+	s.writeResponse(w, link, domain, http.StatusCreated, false)
+}
+
+func (s *ShoterHandler) writeResponse(w http.ResponseWriter, link *model.Link, domain *model.Domain, status int, reused bool) {
 	resp := dto.ShorterResponse{
-		ShortUrl: baseUrl + "/" + alias,
+		ShortUrl:  shortUrl(s.cfg, domain, link.Alias),
+		Status:    string(link.Status(time.Now())),
+		MaxClicks: link.MaxClicks,
+		Reused:    reused,
+	}
+	if link.ActiveFrom != nil {
+		resp.ActiveFrom = link.ActiveFrom.Format(time.RFC3339)
 	}
 	if link.ExpiresAt != nil {
 		resp.ExpiresAt = link.ExpiresAt.Format(time.RFC3339)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(resp)
 }
+
+// createWithGeneratedAlias inserts the link under fresh aliases until one is
+// free. Collisions in a row mean the keyspace is filling up, so the
+// generator is asked for longer aliases.
+func (s *ShoterHandler) createWithGeneratedAlias(ctx context.Context, link *model.Link) error {
+	attempts := s.cfg.Aliases.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultAliasAttempts
+	}
+	growAfter := int64(s.cfg.Aliases.GrowAfter)
+	if growAfter <= 0 {
+		growAfter = defaultAliasGrowAfter
+	}
+
+	for range attempts {
+		var err error
+		link.Alias, err = s.aliases.Generate(ctx)
+		if err != nil {
+			return fmt.Errorf("generate alias: %w", err)
+		}
+		if s.aliasPolicy.Check(link.Alias) != nil {
+			continue
+		}
+
+		err = s.repo.Create(ctx, link)
+		if err == nil {
+			s.collisions.Store(0)
+			return nil
+		}
+		if !isUniqueViolation(err) {
+			return err
+		}
+
+		metrics.AliasCollisionsTotal.Inc()
+		if s.collisions.Add(1)%growAfter == 0 {
+			s.aliases.Grow()
+			s.logger.Warn("alias keyspace is filling up, growing aliases", zap.String("last_alias", link.Alias))
+		}
+	}
+
+	return fmt.Errorf("no free alias after %d attempts", attempts)
+}
+
+// browserSchemes are handled by the browser itself and never open an app.
+var browserSchemes = map[string]bool{
+	"about":            true,
+	"blob":             true,
+	"chrome":           true,
+	"chrome-extension": true,
+	"data":             true,
+	"file":             true,
+	"filesystem":       true,
+	"ftp":              true,
+	"http":             true,
+	"https":            true,
+	"javascript":       true,
+	"jar":              true,
+	"moz-extension":    true,
+	"resource":         true,
+	"vbscript":         true,
+	"view-source":      true,
+	"ws":               true,
+	"wss":              true,
+}
+
+// isAppScheme reports whether an app URI scheme like "myapp" is a custom
+// one, app pages insert app URIs unescaped.
+func isAppScheme(scheme string) bool {
+	return scheme != "" && !browserSchemes[scheme]
+}
+
+func urlScheme(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(u.Scheme)
+}
@@ -2,44 +2,261 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"shorter/internal/auth"
+	"shorter/internal/config"
+	"shorter/internal/export"
+	"shorter/internal/live"
+	"shorter/internal/model"
 	"shorter/internal/repository"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 )
 
+const (
+	defaultSnapshotInterval = 5 * time.Second
+	defaultLiveWriteTimeout = 10 * time.Second
+	// reconnect delay suggested to EventSource clients, ms
+	liveRetry = 3000
+)
+
 type StatsHandler struct {
-	repo   repository.AnalyticsRepository
-	logger *zap.Logger
+	repo     repository.AnalyticsRepository
+	linkRepo repository.LinkRepository
+	domains  repository.DomainRepository
+	exports  repository.ExportRepository
+	hub      *live.Hub
+	logger   *zap.Logger
+	cfg      *config.Config
 }
 
-func NewStatsHandler(repo repository.AnalyticsRepository, logger *zap.Logger) *StatsHandler {
+func NewStatsHandler(
+	repo repository.AnalyticsRepository,
+	linkRepo repository.LinkRepository,
+	domains repository.DomainRepository,
+	exports repository.ExportRepository,
+	hub *live.Hub,
+	logger *zap.Logger,
+	cfg *config.Config,
+) *StatsHandler {
 	return &StatsHandler{
-		repo: repo,
-		logger: logger,
+		repo:     repo,
+		linkRepo: linkRepo,
+		domains:  domains,
+		exports:  exports,
+		hub:      hub,
+		logger:   logger,
+		cfg:      cfg,
 	}
 }
 
 func (s *StatsHandler) Handle(w http.ResponseWriter, r *http.Request) {
-	alias := chi.URLParam(r, "alias")
-	if alias == "" {
-		http.Error(w, "alias is required", http.StatusBadRequest)
+	link, ok := s.getLink(w, r)
+	if !ok {
 		return
 	}
 
-	stats, err := s.repo.GetStats(r.Context(), alias)
+	workspace := auth.MembershipFromContext(r.Context())
+	stats, err := s.repo.GetStats(r.Context(), workspace.WorkspaceID, link)
 	if err != nil {
-		s.logger.Error("fail to get stats", zap.Error(err), zap.String("alias", alias))
+		s.logger.Error("fail to get stats", zap.Error(err), zap.String("alias", link.Alias))
 		http.Error(w, "internal error", http.StatusInternalServerError)
-        return
+		return
 	}
 
 	if stats == nil {
-        http.Error(w, "stats not found", http.StatusNotFound)
-        return
-    }
+		http.Error(w, "stats not found", http.StatusNotFound)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-    json.NewEncoder(w).Encode(stats)
+	json.NewEncoder(w).Encode(stats)
+}
+
+// Live streams clicks of the link as Server-Sent Events while they are
+// saved: "click" events with each click and "snapshot" events with
+// counters every live.snapshot_interval.
+func (s *StatsHandler) Live(w http.ResponseWriter, r *http.Request) {
+	link, ok := s.getLink(w, r)
+	if !ok {
+		return
+	}
+
+	sub := s.hub.Subscribe(link.ID)
+	if sub == nil {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	defer s.hub.Unsubscribe(sub)
+
+	interval := s.cfg.Live.SnapshotInterval
+	if interval <= 0 {
+		interval = defaultSnapshotInterval
+	}
+	writeTimeout := s.cfg.Live.WriteTimeout
+	if writeTimeout <= 0 {
+		writeTimeout = defaultLiveWriteTimeout
+	}
+
+	// the server write timeout would cut the stream, each write gets its
+	// own deadline instead and a client that stops reading is dropped
+	rc := http.NewResponseController(w)
+	send := func(format string, args ...any) bool {
+		if err := rc.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+			return false
+		}
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+	sendEvent := func(event string, data any) bool {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return false
+		}
+		return send("event: %s\ndata: %s\n\n", event, b)
+	}
+
+	workspace := auth.MembershipFromContext(r.Context())
+	snapshot := live.NewSnapshot()
+	sendSnapshot := func() bool {
+		current, err := s.linkRepo.GetByAliasInWorkspace(r.Context(), workspace.WorkspaceID, link.DomainID, link.Alias)
+		if err != nil || current == nil {
+			return false
+		}
+		snapshot.TotalClicks = current.ClickCount
+		snapshot.Dropped = sub.Dropped()
+		return sendEvent("snapshot", snapshot)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if !send("retry: %d\n\n", liveRetry) || !sendSnapshot() {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case click, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			snapshot.Add(click)
+			if !sendEvent("click", click) {
+				return
+			}
+		case <-ticker.C:
+			if !sendSnapshot() {
+				return
+			}
+		}
+	}
+}
+
+// Export streams clicks of the link as CSV, NDJSON or Parquet. Exports
+// covering more than export.async_threshold clicks, or requested with
+// ?async=true, are queued as jobs and answered with 202 and the job.
+func (s *StatsHandler) Export(w http.ResponseWriter, r *http.Request) {
+	link, ok := s.getLink(w, r)
+	if !ok {
+		return
+	}
+
+	q, err := parseExportQuery(r, link)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	async := r.URL.Query().Get("async") == "true"
+	if !async {
+		threshold := s.cfg.Export.AsyncThreshold
+		if threshold <= 0 {
+			threshold = defaultExportAsyncThreshold
+		}
+
+		count, err := s.repo.CountClicks(r.Context(), q)
+		if err != nil {
+			s.logger.Error("fail to count clicks", zap.Error(err), zap.String("alias", link.Alias))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+		async = count > threshold
+	}
+
+	if async {
+		workspace := auth.MembershipFromContext(r.Context())
+		job := &model.ExportJob{ExportQuery: *q, WorkspaceID: workspace.WorkspaceID}
+		if err := s.exports.Create(r.Context(), job); err != nil {
+			s.logger.Error("fail to create export job", zap.Error(err), zap.String("alias", link.Alias))
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Location", exportUrl(s.cfg, job.ID))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(job)
+		return
+	}
+
+	w.Header().Set("Content-Type", export.ContentType(q.Format))
+	w.Header().Set("Content-Disposition", attachment(export.FileName(q)))
+
+	if _, err := export.Run(r.Context(), s.repo, q, newDeadlineWriter(w, exportWriteTimeout)); err != nil {
+		if r.Context().Err() == nil {
+			s.logger.Error("fail to export clicks", zap.Error(err), zap.String("alias", link.Alias))
+		}
+		// the status is already sent, break the connection so the client
+		// does not take a truncated file for a complete one
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// getLink loads the link from the URL within the workspace of the request,
+// on the domain given by the ?domain= query param.
+func (s *StatsHandler) getLink(w http.ResponseWriter, r *http.Request) (*model.Link, bool) {
+	alias := chi.URLParam(r, "alias")
+	if alias == "" {
+		http.Error(w, "alias is required", http.StatusBadRequest)
+		return nil, false
+	}
+
+	workspace := auth.MembershipFromContext(r.Context())
+
+	domain, ok, err := workspaceDomain(r.Context(), s.domains, workspace.WorkspaceID, r.URL.Query().Get("domain"))
+	if err != nil {
+		s.logger.Error("fail to get domain", zap.Error(err))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if !ok {
+		http.Error(w, "stats not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	link, err := s.linkRepo.GetByAliasInWorkspace(r.Context(), workspace.WorkspaceID, domainID(domain), alias)
+	if err != nil {
+		s.logger.Error("fail to get link", zap.Error(err), zap.String("alias", alias))
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if link == nil {
+		http.Error(w, "stats not found", http.StatusNotFound)
+		return nil, false
+	}
+
+	return link, true
 }
@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"net/url"
+	"shorter/internal/config"
+	"shorter/internal/model"
+)
+
+// shortUrl renders the public short URL of a link. Links on custom domains
+// live at the root of their host, others under the public base URL.
+func shortUrl(cfg *config.Config, domain *model.Domain, alias string) string {
+	if domain != nil {
+		u := url.URL{Scheme: cfg.PublicURL().Scheme, Host: domain.Hostname, Path: "/" + alias}
+		return u.String()
+	}
+
+	return cfg.Server.PublicBaseURL + "/" + url.PathEscape(alias)
+}
+
+// publicPath is the path of a link as the visitor's browser sees it,
+// including the base path prefix for links on the default host.
+func publicPath(cfg *config.Config, domain *model.Domain, alias string) string {
+	if domain != nil {
+		return "/" + url.PathEscape(alias)
+	}
+
+	return cfg.BasePath() + "/" + url.PathEscape(alias)
+}
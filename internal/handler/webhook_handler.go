@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"net/http"
+	"shorter/internal/auth"
+	"shorter/internal/dto"
+	"shorter/internal/model"
+	"shorter/internal/repository"
+	"shorter/internal/urlpolicy"
+	"shorter/internal/webhook"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultDeliveriesLimit = 50
+	maxDeliveriesLimit     = 200
+)
+
+type WebhookHandler struct {
+	repo   repository.WebhookRepository
+	policy *urlpolicy.Engine
+	logger *zap.Logger
+}
+
+func NewWebhookHandler(
+	repo repository.WebhookRepository,
+	policy *urlpolicy.Engine,
+	logger *zap.Logger,
+) *WebhookHandler {
+	return &WebhookHandler{
+		repo:   repo,
+		policy: policy,
+		logger: logger,
+	}
+}
+
+// List returns webhooks of the workspace, secrets are not included.
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	workspace := auth.MembershipFromContext(r.Context())
+
+	hooks, err := h.repo.List(r.Context(), workspace.WorkspaceID)
+	if err != nil {
+		h.logger.Error("failed to list webhooks", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return
+	}
+
+	render.JSON(w, r, hooks)
+}
+
+// Create subscribes a URL to events. The response carries the signing
+// secret, which is not shown again.
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req dto.WebhookRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	if err := h.policy.Check(r.Context(), req.Url); err != nil {
+		if v, ok := urlpolicy.AsViolation(err); ok {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			render.JSON(w, r, render.M{"error": "url rejected", "field": "url", "rule": v.Rule, "reason": v.Reason})
+			return
+		}
+		h.logger.Error("url policy check failed", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return
+	}
+
+	secret, err := webhook.GenerateSecret()
+	if err != nil {
+		h.logger.Error("failed to generate webhook secret", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return
+	}
+
+	workspace := auth.MembershipFromContext(r.Context())
+	hook := &model.Webhook{
+		WorkspaceID:     workspace.WorkspaceID,
+		Url:             req.Url,
+		Secret:          secret,
+		Events:          req.Events,
+		ClickThresholds: req.ClickThresholds,
+	}
+	if hook.Subscribed(model.WebhookClickThresholdReached) && len(hook.ClickThresholds) == 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		render.JSON(w, r, render.M{"errors": render.M{"ClickThresholds": "required for click.threshold_reached"}})
+		return
+	}
+
+	if err := h.repo.Create(r.Context(), hook); err != nil {
+		h.logger.Error("failed to create webhook", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	render.JSON(w, r, hook)
+}
+
+// Delete removes the webhook and its delivery log.
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := urlID(w, r, "webhookID", "webhook id")
+	if !ok {
+		return
+	}
+
+	workspace := auth.MembershipFromContext(r.Context())
+	found, err := h.repo.Delete(r.Context(), workspace.WorkspaceID, id)
+	if err != nil {
+		h.logger.Error("failed to delete webhook", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return
+	}
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		render.JSON(w, r, render.M{"error": "webhook not found"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Deliveries returns the delivery log of a webhook, newest first, filtered
+// by ?status=pending|delivered|failed.
+func (h *WebhookHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	hook, ok := h.getWorkspaceWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", model.DeliveryPending, model.DeliveryDelivered, model.DeliveryFailed:
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, render.M{"error": "invalid status"})
+		return
+	}
+
+	limit := defaultDeliveriesLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxDeliveriesLimit {
+			w.WriteHeader(http.StatusBadRequest)
+			render.JSON(w, r, render.M{"error": "limit must be between 1 and " + strconv.Itoa(maxDeliveriesLimit)})
+			return
+		}
+		limit = n
+	}
+
+	deliveries, err := h.repo.Deliveries(r.Context(), hook.ID, status, limit)
+	if err != nil {
+		h.logger.Error("failed to list webhook deliveries", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return
+	}
+
+	render.JSON(w, r, deliveries)
+}
+
+// Replay queues a past delivery again, e.g. after the receiver was fixed.
+func (h *WebhookHandler) Replay(w http.ResponseWriter, r *http.Request) {
+	hook, ok := h.getWorkspaceWebhook(w, r)
+	if !ok {
+		return
+	}
+	deliveryID, ok := urlID(w, r, "deliveryID", "delivery id")
+	if !ok {
+		return
+	}
+
+	delivery, err := h.repo.Replay(r.Context(), hook.ID, deliveryID)
+	if err != nil {
+		h.logger.Error("failed to replay webhook delivery", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return
+	}
+	if delivery == nil {
+		w.WriteHeader(http.StatusNotFound)
+		render.JSON(w, r, render.M{"error": "delivery not found"})
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	render.JSON(w, r, delivery)
+}
+
+func (h *WebhookHandler) getWorkspaceWebhook(w http.ResponseWriter, r *http.Request) (*model.Webhook, bool) {
+	id, ok := urlID(w, r, "webhookID", "webhook id")
+	if !ok {
+		return nil, false
+	}
+
+	workspace := auth.MembershipFromContext(r.Context())
+	hook, err := h.repo.Get(r.Context(), workspace.WorkspaceID, id)
+	if err != nil {
+		h.logger.Error("failed to get webhook", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return nil, false
+	}
+	if hook == nil {
+		w.WriteHeader(http.StatusNotFound)
+		render.JSON(w, r, render.M{"error": "webhook not found"})
+		return nil, false
+	}
+
+	return hook, true
+}
+
+// urlID parses a numeric URL param, name is used in the error message.
+func urlID(w http.ResponseWriter, r *http.Request, param, name string) (int64, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, param), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, render.M{"error": "invalid " + name})
+		return 0, false
+	}
+
+	return id, true
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"shorter/internal/auth"
+	"shorter/internal/dto"
+	"shorter/internal/model"
+	"shorter/internal/repository"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// validate is shared by handlers, it caches struct rules and is safe for
+// concurrent use.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	// looser than the built-in rule so app links like myapp://product/42 pass,
+	// destinations are checked by the url policy anyway
+	v.RegisterValidation("url", func(fl validator.FieldLevel) bool {
+		_, err := url.ParseRequestURI(fl.Field().String())
+		return err == nil
+	})
+
+	return v
+}
+
+type WorkspaceHandler struct {
+	repo   repository.WorkspaceRepository
+	logger *zap.Logger
+}
+
+func NewWorkspaceHandler(repo repository.WorkspaceRepository, logger *zap.Logger) *WorkspaceHandler {
+	return &WorkspaceHandler{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// List returns workspaces the authenticated key is a member of.
+func (h *WorkspaceHandler) List(w http.ResponseWriter, r *http.Request) {
+	key := auth.KeyFromContext(r.Context())
+
+	memberships, err := h.repo.ListMemberships(r.Context(), key.ID)
+	if err != nil {
+		h.logger.Error("failed to list workspaces", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return
+	}
+
+	render.JSON(w, r, memberships)
+}
+
+// Create makes a new workspace with the authenticated key as its admin.
+func (h *WorkspaceHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req dto.WorkspaceRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	key := auth.KeyFromContext(r.Context())
+	ws := &model.Workspace{Name: req.Name}
+	if err := h.repo.Create(r.Context(), ws, key.ID); err != nil {
+		h.logger.Error("failed to create workspace", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	render.JSON(w, r, ws)
+}
+
+// SetMember adds a key to the workspace or changes its role. Admin only.
+func (h *WorkspaceHandler) SetMember(w http.ResponseWriter, r *http.Request) {
+	keyID, ok := memberKeyID(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.WorkspaceMemberRequest
+	if !decodeAndValidate(w, r, &req) {
+		return
+	}
+
+	workspace := auth.MembershipFromContext(r.Context())
+	if keyID == workspace.APIKeyID && model.Role(req.Role) != model.RoleAdmin {
+		w.WriteHeader(http.StatusConflict)
+		render.JSON(w, r, render.M{"error": "admins cannot demote themselves"})
+		return
+	}
+
+	if err := h.repo.SetMember(r.Context(), workspace.WorkspaceID, keyID, model.Role(req.Role)); err != nil {
+		if isForeignKeyViolation(err) {
+			w.WriteHeader(http.StatusNotFound)
+			render.JSON(w, r, render.M{"error": "api key not found"})
+			return
+		}
+		h.logger.Error("failed to set workspace member", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return
+	}
+
+	render.JSON(w, r, model.Membership{
+		WorkspaceID:   workspace.WorkspaceID,
+		WorkspaceName: workspace.WorkspaceName,
+		APIKeyID:      keyID,
+		Role:          model.Role(req.Role),
+	})
+}
+
+// RemoveMember takes a key out of the workspace. Admin only.
+func (h *WorkspaceHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	keyID, ok := memberKeyID(w, r)
+	if !ok {
+		return
+	}
+
+	workspace := auth.MembershipFromContext(r.Context())
+	if keyID == workspace.APIKeyID {
+		w.WriteHeader(http.StatusConflict)
+		render.JSON(w, r, render.M{"error": "admins cannot remove themselves"})
+		return
+	}
+
+	found, err := h.repo.RemoveMember(r.Context(), workspace.WorkspaceID, keyID)
+	if err != nil {
+		h.logger.Error("failed to remove workspace member", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		render.JSON(w, r, render.M{"error": "internal error"})
+		return
+	}
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		render.JSON(w, r, render.M{"error": "member not found"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func memberKeyID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	keyID, err := strconv.ParseInt(chi.URLParam(r, "keyID"), 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, render.M{"error": "invalid key id"})
+		return 0, false
+	}
+
+	return keyID, true
+}
+
+// decodeAndValidate reads a JSON body into req and runs struct validation,
+// writing the error response itself when something is wrong.
+func decodeAndValidate(w http.ResponseWriter, r *http.Request, req any) bool {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		render.JSON(w, r, render.M{"error": err.Error()})
+		return false
+	}
+
+	if err := validate.Struct(req); err != nil {
+		errs := make(map[string]string)
+		for _, e := range err.(validator.ValidationErrors) {
+			errs[e.Field()] = e.Error()
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		render.JSON(w, r, render.M{"errors": errs})
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,107 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"shorter/internal/model"
+	"shorter/internal/urlpolicy"
+	"time"
+)
+
+const (
+	maxRedirects = 10
+	// enough of a GET body to let the server finish the response
+	maxBodyRead = 64 << 10
+)
+
+// errTooManyRedirects fails the check, a redirect loop never reaches the
+// destination.
+var errTooManyRedirects = errors.New("too many redirects")
+
+// Checker probes a destination URL.
+type Checker struct {
+	client    *http.Client
+	userAgent string
+}
+
+// NewChecker builds a checker, with publicOnly it refuses to connect to
+// private and loopback addresses.
+func NewChecker(timeout time.Duration, userAgent string, publicOnly bool) *Checker {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	if publicOnly {
+		dialer.Control = urlpolicy.PublicDialControl
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.MaxIdleConnsPerHost = 2
+	if publicOnly {
+		// a proxy would be the only address the dial guard gets to see
+		transport.Proxy = nil
+	}
+
+	return &Checker{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return errTooManyRedirects
+				}
+				return nil
+			},
+		},
+		userAgent: userAgent,
+	}
+}
+
+// Check sends HEAD and falls back to GET for servers that do not support
+// it. The result is ok for any final status below 400.
+func (c *Checker) Check(ctx context.Context, linkID int64, rawURL string) (*model.LinkHealth, bool) {
+	health := &model.LinkHealth{LinkID: linkID, CheckedAt: time.Now().UTC()}
+
+	start := time.Now()
+	resp, err := c.do(ctx, http.MethodHead, rawURL)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		resp.Body.Close()
+		start = time.Now()
+		resp, err = c.do(ctx, http.MethodGet, rawURL)
+	}
+	health.LatencyMs = int(time.Since(start).Milliseconds())
+
+	if err != nil {
+		msg := err.Error()
+		health.Error = &msg
+		return health, false
+	}
+	defer resp.Body.Close()
+	io.CopyN(io.Discard, resp.Body, maxBodyRead)
+
+	status := resp.StatusCode
+	health.StatusCode = &status
+	finalUrl := resp.Request.URL.String()
+	health.FinalUrl = &finalUrl
+	if resp.TLS != nil && len(resp.TLS.PeerCertificates) > 0 {
+		expires := resp.TLS.PeerCertificates[0].NotAfter
+		health.TLSExpiresAt = &expires
+	}
+
+	return health, status < http.StatusBadRequest
+}
+
+func (c *Checker) do(ctx context.Context, method, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+
+	return c.client.Do(req)
+}
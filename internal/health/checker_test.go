@@ -0,0 +1,209 @@
+package health
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"shorter/internal/model"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// loopback servers are private, the tests check them without the guard
+func newTestChecker() *Checker {
+	return NewChecker(2*time.Second, "test", false)
+}
+
+func TestCheckFallsBackToGet(t *testing.T) {
+	var methods []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	health, ok := newTestChecker().Check(context.Background(), 1, srv.URL)
+	if !ok {
+		t.Fatalf("check failed: %+v", health)
+	}
+	if len(methods) != 2 || methods[0] != http.MethodHead || methods[1] != http.MethodGet {
+		t.Errorf("methods = %v, want [HEAD GET]", methods)
+	}
+	if health.StatusCode == nil || *health.StatusCode != http.StatusOK {
+		t.Errorf("status = %v, want 200", health.StatusCode)
+	}
+}
+
+func TestCheckFollowsRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/middle", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/middle", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/final", http.StatusFound)
+	})
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	health, ok := newTestChecker().Check(context.Background(), 1, srv.URL+"/start")
+	if !ok {
+		t.Fatalf("check failed: %+v", health)
+	}
+	if want := srv.URL + "/final"; health.FinalUrl == nil || *health.FinalUrl != want {
+		t.Errorf("final url = %v, want %s", health.FinalUrl, want)
+	}
+}
+
+func TestCheckRedirectLoopFails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Path, http.StatusFound)
+	}))
+	defer srv.Close()
+
+	health, ok := newTestChecker().Check(context.Background(), 1, srv.URL+"/loop")
+	if ok {
+		t.Fatalf("redirect loop passed as healthy: %+v", health)
+	}
+	if health.Error == nil {
+		t.Error("error is not recorded")
+	}
+}
+
+func TestCheckServerErrorFails(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	health, ok := newTestChecker().Check(context.Background(), 1, srv.URL)
+	if ok {
+		t.Fatal("5xx passed as healthy")
+	}
+	if health.StatusCode == nil || *health.StatusCode != http.StatusBadGateway {
+		t.Errorf("status = %v, want 502", health.StatusCode)
+	}
+	if health.Error != nil {
+		t.Errorf("error = %q, a response is not a transport error", *health.Error)
+	}
+}
+
+func TestCheckRecordsTLSExpiry(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	checker := newTestChecker()
+	checker.client.Transport.(*http.Transport).TLSClientConfig = &tls.Config{RootCAs: roots}
+
+	health, ok := checker.Check(context.Background(), 1, srv.URL)
+	if !ok {
+		t.Fatalf("check failed: %+v", health)
+	}
+	if want := srv.Certificate().NotAfter; health.TLSExpiresAt == nil || !health.TLSExpiresAt.Equal(want) {
+		t.Errorf("tls expiry = %v, want %v", health.TLSExpiresAt, want)
+	}
+}
+
+// fakeHealthRepository counts failures the way PgHealthRepository.Save does.
+type fakeHealthRepository struct {
+	mu      sync.Mutex
+	targets []model.HealthTarget
+	saved   map[int64]model.LinkHealth
+}
+
+func (r *fakeHealthRepository) DueLinks(_ context.Context, _ time.Time, _ int) ([]model.HealthTarget, error) {
+	return r.targets, nil
+}
+
+func (r *fakeHealthRepository) Save(_ context.Context, health *model.LinkHealth, ok bool, threshold int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prev := r.saved[health.LinkID]
+	if ok {
+		health.ConsecutiveFailures = 0
+		health.Broken = false
+		health.BrokenSince = nil
+	} else {
+		health.ConsecutiveFailures = prev.ConsecutiveFailures + 1
+		health.Broken = health.ConsecutiveFailures >= threshold
+		health.BrokenSince = prev.BrokenSince
+		if health.Broken && health.BrokenSince == nil {
+			health.BrokenSince = &health.CheckedAt
+		}
+	}
+	r.saved[health.LinkID] = *health
+
+	return nil
+}
+
+func (r *fakeHealthRepository) GetByLinkID(_ context.Context, linkID int64) (*model.LinkHealth, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	health, ok := r.saved[linkID]
+	if !ok {
+		return nil, nil
+	}
+
+	return &health, nil
+}
+
+func (r *fakeHealthRepository) CountBroken(_ context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for _, health := range r.saved {
+		if health.Broken {
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+func TestMonitorMarksBrokenAtThreshold(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	const threshold = 3
+	repo := &fakeHealthRepository{
+		targets: []model.HealthTarget{{LinkID: 7, Url: srv.URL}},
+		saved:   make(map[int64]model.LinkHealth),
+	}
+	core, logs := observer.New(zapcore.WarnLevel)
+	monitor := NewMonitor(repo, newTestChecker(), time.Hour, 10, 1, threshold, zap.New(core))
+
+	ctx := context.Background()
+	for run := 1; run <= threshold+1; run++ {
+		monitor.runBatch(ctx)
+
+		health, _ := repo.GetByLinkID(ctx, 7)
+		if health.ConsecutiveFailures != run {
+			t.Fatalf("run %d: failures = %d", run, health.ConsecutiveFailures)
+		}
+		if wantBroken := run >= threshold; health.Broken != wantBroken {
+			t.Fatalf("run %d: broken = %v, want %v", run, health.Broken, wantBroken)
+		}
+	}
+
+	if n := logs.FilterMessage("link destination is broken").Len(); n != 1 {
+		t.Errorf("broken link reported %d times, want once", n)
+	}
+}
@@ -0,0 +1,143 @@
+package health
+
+import (
+	"context"
+	"shorter/internal/metrics"
+	"shorter/internal/repository"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultInterval    = 6 * time.Hour
+	defaultBatchSize   = 200
+	defaultConcurrency = 8
+	defaultThreshold   = 3
+)
+
+// Monitor periodically checks the destinations of active links and marks
+// links broken after threshold failures in a row.
+type Monitor struct {
+	repo        repository.HealthRepository
+	checker     *Checker
+	interval    time.Duration
+	tick        time.Duration
+	batchSize   int
+	concurrency int
+	threshold   int
+	logger      *zap.Logger
+}
+
+func NewMonitor(
+	repo repository.HealthRepository,
+	checker *Checker,
+	interval time.Duration,
+	batchSize int,
+	concurrency int,
+	threshold int,
+	logger *zap.Logger,
+) *Monitor {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+	if threshold <= 0 {
+		threshold = defaultThreshold
+	}
+
+	return &Monitor{
+		repo:        repo,
+		checker:     checker,
+		interval:    interval,
+		tick:        time.Minute,
+		batchSize:   batchSize,
+		concurrency: concurrency,
+		threshold:   threshold,
+		logger:      logger,
+	}
+}
+
+// Start runs until ctx is cancelled. Every tick it checks a batch of links
+// whose last check is older than the interval.
+func (m *Monitor) Start(ctx context.Context) error {
+	m.logger.Info("starting link health monitor", zap.Duration("interval", m.interval))
+
+	ticker := time.NewTicker(m.tick)
+	defer ticker.Stop()
+
+	for {
+		m.runBatch(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Monitor) runBatch(ctx context.Context) {
+	targets, err := m.repo.DueLinks(ctx, time.Now().Add(-m.interval), m.batchSize)
+	if err != nil {
+		if ctx.Err() == nil {
+			m.logger.Error("failed to load links for health check", zap.Error(err))
+		}
+		return
+	}
+
+	jobs := make(chan int, len(targets))
+	for i := range targets {
+		jobs <- i
+	}
+	close(jobs)
+
+	var wg sync.WaitGroup
+	for range min(m.concurrency, len(targets)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if ctx.Err() != nil {
+					return
+				}
+				m.check(ctx, targets[i].LinkID, targets[i].Url)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if broken, err := m.repo.CountBroken(ctx); err == nil {
+		metrics.LinksBroken.Set(float64(broken))
+	}
+}
+
+func (m *Monitor) check(ctx context.Context, linkID int64, url string) {
+	health, ok := m.checker.Check(ctx, linkID, url)
+
+	result := "ok"
+	if !ok {
+		result = "failed"
+	}
+	metrics.HealthChecksTotal.WithLabelValues(result).Inc()
+	metrics.HealthCheckDuration.Observe(float64(health.LatencyMs) / 1000)
+
+	if err := m.repo.Save(ctx, health, ok, m.threshold); err != nil {
+		m.logger.Error("failed to save link health", zap.Error(err), zap.Int64("link_id", linkID))
+		return
+	}
+	// log once, when the link crosses the threshold
+	if health.Broken && health.ConsecutiveFailures == m.threshold {
+		m.logger.Warn("link destination is broken",
+			zap.Int64("link_id", linkID),
+			zap.String("url", url),
+			zap.Int("failures", health.ConsecutiveFailures),
+		)
+	}
+}
@@ -0,0 +1,138 @@
+package live
+
+import (
+	"shorter/internal/enricher"
+	"shorter/internal/metrics"
+	"sync"
+	"sync/atomic"
+)
+
+const defaultBuffer = 64
+
+// Click is an enriched click as streamed to dashboards, without the IP.
+type Click struct {
+	Alias     string  `json:"alias"`
+	Timestamp string  `json:"timestamp"`
+	Country   *string `json:"country,omitempty"`
+	City      *string `json:"city,omitempty"`
+	Device    string  `json:"device"`
+	OS        string  `json:"os"`
+	Browser   string  `json:"browser"`
+	Referer   *string `json:"referer,omitempty"`
+	Variant   *string `json:"variant,omitempty"`
+}
+
+// Hub fans saved clicks out to the subscribers of their link. Publishing
+// never blocks: a subscriber that does not keep up loses clicks, which are
+// counted instead.
+type Hub struct {
+	mu     sync.Mutex
+	subs   map[int64]map[*Subscriber]struct{}
+	buffer int
+	closed bool
+}
+
+type Subscriber struct {
+	C       <-chan Click
+	c       chan Click
+	linkID  int64
+	dropped atomic.Int64
+}
+
+// Dropped returns the number of clicks lost because the buffer was full.
+func (s *Subscriber) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+func NewHub(buffer int) *Hub {
+	if buffer <= 0 {
+		buffer = defaultBuffer
+	}
+
+	return &Hub{
+		subs:   make(map[int64]map[*Subscriber]struct{}),
+		buffer: buffer,
+	}
+}
+
+// Subscribe starts receiving clicks of the link. It returns nil once the
+// hub is closed.
+func (h *Hub) Subscribe(linkID int64) *Subscriber {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil
+	}
+
+	c := make(chan Click, h.buffer)
+	sub := &Subscriber{C: c, c: c, linkID: linkID}
+	if h.subs[linkID] == nil {
+		h.subs[linkID] = make(map[*Subscriber]struct{})
+	}
+	h.subs[linkID][sub] = struct{}{}
+	metrics.LiveSubscribers.Inc()
+
+	return sub
+}
+
+func (h *Hub) Unsubscribe(sub *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs := h.subs[sub.linkID]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(h.subs, sub.linkID)
+	}
+	close(sub.c)
+	metrics.LiveSubscribers.Dec()
+}
+
+func (h *Hub) Publish(click *enricher.EnrichedClick) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	subs := h.subs[click.LinkID]
+	if len(subs) == 0 {
+		return
+	}
+
+	c := Click{
+		Alias:     click.Alias,
+		Timestamp: click.Timestamp,
+		Country:   click.Country,
+		City:      click.City,
+		Device:    click.Device,
+		OS:        click.OS,
+		Browser:   click.Browser,
+		Referer:   click.Referer,
+		Variant:   click.Variant,
+	}
+	for sub := range subs {
+		select {
+		case sub.c <- c:
+		default:
+			sub.dropped.Add(1)
+			metrics.LiveDroppedTotal.Inc()
+		}
+	}
+}
+
+// Close ends every subscription, streams see their channel closed.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for linkID, subs := range h.subs {
+		for sub := range subs {
+			close(sub.c)
+			metrics.LiveSubscribers.Dec()
+		}
+		delete(h.subs, linkID)
+	}
+}
@@ -0,0 +1,29 @@
+package live
+
+// Snapshot is the periodic counter summary of a stream. Clicks and the
+// breakdowns cover what the stream received since it was opened.
+type Snapshot struct {
+	TotalClicks int            `json:"total_clicks"`
+	Clicks      int            `json:"clicks"`
+	ByCountry   map[string]int `json:"by_country"`
+	ByDevice    map[string]int `json:"by_device"`
+	// clicks not sent because the client was too slow
+	Dropped int64 `json:"dropped"`
+}
+
+func NewSnapshot() *Snapshot {
+	return &Snapshot{
+		ByCountry: make(map[string]int),
+		ByDevice:  make(map[string]int),
+	}
+}
+
+func (s *Snapshot) Add(c Click) {
+	s.Clicks++
+	country := "unknown"
+	if c.Country != nil && *c.Country != "" {
+		country = *c.Country
+	}
+	s.ByCountry[country]++
+	s.ByDevice[c.Device]++
+}
@@ -22,6 +22,91 @@ var (
 		},
 	)
 
+	UnlockFailuresTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "shorter",
+			Subsystem: "http",
+			Name: "unlock_failures_total",
+			Help: "Total number of rejected password attempts for protected links",
+		},
+	)
+
+	AliasCollisionsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "shorter",
+			Subsystem: "links",
+			Name: "alias_collisions_total",
+			Help: "Total number of generated aliases that were already taken",
+		},
+	)
+
+	RateLimitRejectedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "shorter",
+			Subsystem: "http",
+			Name: "rate_limit_rejected_total",
+			Help: "Total number of requests rejected by rate limiting by policy",
+		},
+		[]string{"policy"},
+	)
+
+	HealthChecksTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "shorter",
+			Subsystem: "health",
+			Name: "checks_total",
+			Help: "Total number of link destination checks by result",
+		},
+		[]string{"result"},
+	)
+
+	HealthCheckDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "shorter",
+			Subsystem: "health",
+			Name: "check_duration_seconds",
+			Help: "Link destination check duration in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
+
+	LinksBroken = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "shorter",
+			Subsystem: "health",
+			Name: "broken_links",
+			Help: "Number of links whose destination is currently broken",
+		},
+	)
+
+	WebhookDeliveriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "shorter",
+			Subsystem: "webhooks",
+			Name: "deliveries_total",
+			Help: "Total number of webhook delivery attempts by result",
+		},
+		[]string{"result"},
+	)
+
+	LiveSubscribers = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "shorter",
+			Subsystem: "live",
+			Name: "subscribers",
+			Help: "Number of open live click streams",
+		},
+	)
+
+	LiveDroppedTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: "shorter",
+			Subsystem: "live",
+			Name: "dropped_total",
+			Help: "Total number of clicks not sent to slow live stream clients",
+		},
+	)
+
 	EnrichDuration = prometheus.NewHistogram(
 		prometheus.HistogramOpts{
 			Namespace: "shorter",
@@ -45,6 +130,15 @@ var (
 func Register() {
 	prometheus.MustRegister(RedirectsTotal)
 	prometheus.MustRegister(RedirectsErrorTotal)
+	prometheus.MustRegister(UnlockFailuresTotal)
+	prometheus.MustRegister(RateLimitRejectedTotal)
+	prometheus.MustRegister(AliasCollisionsTotal)
+	prometheus.MustRegister(HealthChecksTotal)
+	prometheus.MustRegister(HealthCheckDuration)
+	prometheus.MustRegister(LinksBroken)
+	prometheus.MustRegister(WebhookDeliveriesTotal)
+	prometheus.MustRegister(LiveSubscribers)
+	prometheus.MustRegister(LiveDroppedTotal)
 	prometheus.MustRegister(EnrichDuration)
 	prometheus.MustRegister(EventsProcessed)
 }
\ No newline at end of file
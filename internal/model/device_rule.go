@@ -0,0 +1,28 @@
+package model
+
+import "slices"
+
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformMobile  = "mobile"
+	PlatformDesktop = "desktop"
+)
+
+// DeviceRule sends visitors on the listed platforms to another destination.
+// With AppUrl set the app is opened first and Url (usually the store) is the
+// fallback when the app is not installed.
+type DeviceRule struct {
+	Platforms []string `json:"platforms"`
+	Url       string   `json:"url"`
+	AppUrl    string   `json:"app_url,omitempty"`
+}
+
+// DeviceRule returns the index of the first rule matching the platform or
+// -1, "mobile" in a rule matches any phone or tablet.
+func (l *Link) DeviceRule(platform string) int {
+	return slices.IndexFunc(l.DeviceRules, func(rule DeviceRule) bool {
+		return slices.Contains(rule.Platforms, platform) ||
+			(platform != PlatformDesktop && slices.Contains(rule.Platforms, PlatformMobile))
+	})
+}
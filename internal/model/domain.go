@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+// Domain is a branded short host. Links without a domain live on the
+// default host of the service.
+type Domain struct {
+	ID          int64     `json:"id"`
+	Hostname    string    `json:"hostname"`
+	WorkspaceID *int64    `json:"workspace_id,omitempty"`
+	FallbackUrl *string   `json:"fallback_url,omitempty"`
+	NotFoundUrl *string   `json:"not_found_url,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// IsUsableBy reports whether links of the workspace may be created on the
+// domain. Domains without a workspace are shared.
+func (d *Domain) IsUsableBy(workspaceID int64) bool {
+	return d.WorkspaceID == nil || *d.WorkspaceID == workspaceID
+}
@@ -0,0 +1,69 @@
+package model
+
+import "time"
+
+const (
+	ExportCSV     = "csv"
+	ExportNDJSON  = "ndjson"
+	ExportParquet = "parquet"
+)
+
+const (
+	ExportPending = "pending"
+	ExportRunning = "running"
+	ExportDone    = "done"
+	ExportFailed  = "failed"
+)
+
+// ExportQuery selects clicks of a link to export. With Aggregate set to
+// "hour" or "day" rows are counts per period, country and device instead
+// of single clicks. From is inclusive, To exclusive.
+type ExportQuery struct {
+	LinkID    int64      `json:"-"`
+	Alias     string     `json:"alias"`
+	Format    string     `json:"format"`
+	Aggregate string     `json:"aggregate,omitempty"`
+	From      *time.Time `json:"from,omitempty"`
+	To        *time.Time `json:"to,omitempty"`
+}
+
+// ExportJob is an export too large to stream in the request, written to
+// a file in the background.
+type ExportJob struct {
+	ExportQuery
+
+	ID          int64      `json:"id"`
+	WorkspaceID int64      `json:"workspace_id"`
+	Status      string     `json:"status"`
+	Rows        *int64     `json:"rows,omitempty"`
+	Error       *string    `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	DownloadUrl string     `json:"download_url,omitempty"`
+
+	FilePath *string `json:"-"`
+}
+
+// ClickRow is a raw enriched click.
+type ClickRow struct {
+	Timestamp time.Time `json:"timestamp"`
+	Alias     string    `json:"alias"`
+	IP        *string   `json:"ip"`
+	Country   *string   `json:"country"`
+	City      *string   `json:"city"`
+	Device    *string   `json:"device"`
+	OS        *string   `json:"os"`
+	Browser   *string   `json:"browser"`
+	Referer   *string   `json:"referer"`
+	Variant   *string   `json:"variant"`
+}
+
+// ClickAggregate counts clicks of a period by country and device.
+type ClickAggregate struct {
+	Period    time.Time `json:"period"`
+	Country   *string   `json:"country"`
+	Device    *string   `json:"device"`
+	Clicks    int64     `json:"clicks"`
+	UniqueIPs int64     `json:"unique_ips"`
+}
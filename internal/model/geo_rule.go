@@ -0,0 +1,23 @@
+package model
+
+import (
+	"slices"
+	"strings"
+)
+
+// GeoRule sends visitors from the listed countries to another destination.
+type GeoRule struct {
+	Countries []string `json:"countries"`
+	Url       string   `json:"url"`
+}
+
+// GeoRule returns the index of the first rule listing the country or -1.
+func (l *Link) GeoRule(country string) int {
+	if country == "" {
+		return -1
+	}
+
+	return slices.IndexFunc(l.GeoRules, func(rule GeoRule) bool {
+		return slices.ContainsFunc(rule.Countries, func(c string) bool { return strings.EqualFold(c, country) })
+	})
+}
@@ -2,10 +2,71 @@ package model
 
 import "time"
 
+type LinkStatus string
+
+const (
+	LinkStatusScheduled LinkStatus = "scheduled"
+	LinkStatusActive    LinkStatus = "active"
+	LinkStatusExpired   LinkStatus = "expired"
+	LinkStatusDisabled  LinkStatus = "disabled"
+)
+
 type Link struct {
-	Alias       string     `json:"alias" db:"aliaZ"`
-	OriginalUrl string     `json:"original_url"`
-	CreatedAt   time.Time  `json:"created_at"`
-	ExpiresAt   *time.Time `json:"expires_at"`
-	ClickCount  int        `json:"click_count"`
+	ID                   int64         `json:"id"`
+	Alias                string        `json:"alias" db:"aliaZ"`
+	OriginalUrl          string        `json:"original_url"`
+	CreatedAt            time.Time     `json:"created_at"`
+	ActiveFrom           *time.Time    `json:"active_from,omitempty"`
+	ScheduledRedirectUrl *string       `json:"scheduled_redirect_url,omitempty"`
+	ExpiresAt            *time.Time    `json:"expires_at"`
+	ExpiredRedirectUrl   *string       `json:"expired_redirect_url,omitempty"`
+	ClickCount           int           `json:"click_count"`
+	MaxClicks            *int          `json:"max_clicks,omitempty"`
+	PasswordHash         *string       `json:"-"`
+	IsActive             bool          `json:"is_active"`
+	OwnerID              *int64        `json:"owner_id,omitempty"`
+	WorkspaceID          *int64        `json:"workspace_id,omitempty"`
+	DomainID             *int64        `json:"domain_id,omitempty"`
+	UrlHash              *string       `json:"-"`
+	GeoRules             []GeoRule     `json:"geo_rules,omitempty"`
+	DeviceRules          []DeviceRule  `json:"device_rules,omitempty"`
+	RoutingRules         []RoutingRule `json:"routing_rules,omitempty"`
+	Variants             []Variant     `json:"variants,omitempty"`
+	StickyVariants       bool          `json:"sticky_variants"`
+	Preview              bool          `json:"preview"`
+	Meta                 *LinkMeta     `json:"meta,omitempty"`
+	Pixels               []Pixel       `json:"pixels,omitempty"`
+	PixelDelayMs         *int          `json:"pixel_delay_ms,omitempty"`
+}
+
+// Status computes the lifecycle state of the link at the given moment.
+func (l *Link) Status(now time.Time) LinkStatus {
+	switch {
+	case !l.IsActive:
+		return LinkStatusDisabled
+	case l.IsExpired(now):
+		return LinkStatusExpired
+	case l.ActiveFrom != nil && now.Before(*l.ActiveFrom):
+		return LinkStatusScheduled
+	default:
+		return LinkStatusActive
+	}
+}
+
+func (l *Link) IsExpired(now time.Time) bool {
+	return l.ExpiresAt != nil && !now.Before(*l.ExpiresAt)
+}
+
+// IsExhausted reports whether a click-limited link has used up all its clicks.
+func (l *Link) IsExhausted() bool {
+	return l.MaxClicks != nil && l.ClickCount >= *l.MaxClicks
+}
+
+// HasTargeting reports whether the destination depends on the visitor.
+func (l *Link) HasTargeting() bool {
+	return len(l.RoutingRules) > 0 || len(l.DeviceRules) > 0 || len(l.GeoRules) > 0
+}
+
+func (l *Link) IsProtected() bool {
+	return l.PasswordHash != nil && *l.PasswordHash != ""
 }
@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// LinkHealth is the latest probe of a link's destination.
+type LinkHealth struct {
+	LinkID              int64      `json:"-"`
+	CheckedAt           time.Time  `json:"checked_at"`
+	StatusCode          *int       `json:"status_code,omitempty"`
+	LatencyMs           int        `json:"latency_ms"`
+	FinalUrl            *string    `json:"final_url,omitempty"`
+	TLSExpiresAt        *time.Time `json:"tls_expires_at,omitempty"`
+	Error               *string    `json:"error,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	Broken              bool       `json:"broken"`
+	BrokenSince         *time.Time `json:"broken_since,omitempty"`
+}
+
+// HealthTarget is a link due for a health check.
+type HealthTarget struct {
+	LinkID int64
+	Url    string
+}
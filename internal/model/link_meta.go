@@ -0,0 +1,9 @@
+package model
+
+// LinkMeta overrides the OpenGraph and Twitter card shown when the short
+// link is shared.
+type LinkMeta struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+}
@@ -0,0 +1,15 @@
+package model
+
+const (
+	PixelImage   = "image"
+	PixelScript  = "script"
+	PixelSnippet = "html"
+)
+
+// Pixel is fired on an intermediate page before the visitor is forwarded:
+// an image URL, an external script or a raw HTML snippet.
+type Pixel struct {
+	Type    string `json:"type"`
+	Url     string `json:"url,omitempty"`
+	Snippet string `json:"snippet,omitempty"`
+}
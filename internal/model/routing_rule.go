@@ -0,0 +1,38 @@
+package model
+
+// RoutingRule sends clicks matching all of its conditions to Url. Rules of
+// a link are evaluated in order, the first match wins.
+type RoutingRule struct {
+	Name string     `json:"name,omitempty"`
+	When Conditions `json:"when"`
+	Url  string     `json:"url"`
+}
+
+// Conditions are combined with AND, values inside one condition with OR.
+// Empty conditions match everything.
+type Conditions struct {
+	Countries      []string          `json:"countries,omitempty"`
+	Devices        []string          `json:"devices,omitempty"`
+	OS             []string          `json:"os,omitempty"`
+	Browsers       []string          `json:"browsers,omitempty"`
+	Languages      []string          `json:"languages,omitempty"`
+	Weekdays       []string          `json:"weekdays,omitempty"`
+	Hours          *HourRange        `json:"hours,omitempty"`
+	Timezone       string            `json:"timezone,omitempty"`
+	RefererDomains []string          `json:"referer_domains,omitempty"`
+	Query          map[string]string `json:"query,omitempty"`
+}
+
+// HourRange is [From, To) in hours, From > To wraps around midnight.
+type HourRange struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+func (h *HourRange) Contains(hour int) bool {
+	if h.From <= h.To {
+		return hour >= h.From && hour < h.To
+	}
+
+	return hour >= h.From || hour < h.To
+}
@@ -0,0 +1,42 @@
+package model
+
+// Variant is one of several weighted destinations of a link, used for A/B
+// tests and rotation.
+type Variant struct {
+	Name   string `json:"name"`
+	Url    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// PickVariant chooses a variant with probability proportional to its
+// weight, intn must return a number in [0, n).
+func (l *Link) PickVariant(intn func(n int) int) *Variant {
+	total := 0
+	for _, v := range l.Variants {
+		total += v.Weight
+	}
+	if total <= 0 {
+		return nil
+	}
+
+	n := intn(total)
+	for i, v := range l.Variants {
+		if n < v.Weight {
+			return &l.Variants[i]
+		}
+		n -= v.Weight
+	}
+
+	return nil
+}
+
+// VariantByName returns the variant or nil when it no longer exists.
+func (l *Link) VariantByName(name string) *Variant {
+	for i, v := range l.Variants {
+		if v.Name == name {
+			return &l.Variants[i]
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+const (
+	WebhookLinkCreated           = "link.created"
+	WebhookLinkUpdated           = "link.updated"
+	WebhookLinkExpired           = "link.expired"
+	WebhookClickRecorded         = "click.recorded"
+	WebhookClickThresholdReached = "click.threshold_reached"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook subscribes a URL of the workspace to events. The secret signs
+// deliveries and is only shown when the webhook is created.
+type Webhook struct {
+	ID              int64     `json:"id"`
+	WorkspaceID     int64     `json:"workspace_id"`
+	Url             string    `json:"url"`
+	Secret          string    `json:"secret,omitempty"`
+	Events          []string  `json:"events"`
+	ClickThresholds []int     `json:"click_thresholds,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+func (h *Webhook) Subscribed(event string) bool {
+	return slices.Contains(h.Events, event)
+}
+
+// WebhookDelivery is one event queued for a webhook together with the
+// outcome of its latest attempt.
+type WebhookDelivery struct {
+	ID             int64           `json:"id"`
+	WebhookID      int64           `json:"webhook_id"`
+	Event          string          `json:"event"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	ResponseStatus *int            `json:"response_status,omitempty"`
+	Error          *string         `json:"error,omitempty"`
+	ReplayOf       *int64          `json:"replay_of,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+
+	// target of a delivery claimed for sending
+	Url    string `json:"-"`
+	Secret string `json:"-"`
+}
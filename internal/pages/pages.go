@@ -0,0 +1,54 @@
+package pages
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"net/http"
+	"path/filepath"
+)
+
+//go:embed templates/*.html
+var defaultTemplates embed.FS
+
+// Renderer serves the HTML pages shown on the public redirect path.
+// Templates found in the configured directory replace the built-in ones
+// with the same file name, e.g. expired.html or not_found.html.
+type Renderer struct {
+	tmpl *template.Template
+}
+
+func NewRenderer(dir string) (*Renderer, error) {
+	tmpl, err := template.ParseFS(defaultTemplates, "templates/*.html")
+	if err != nil {
+		return nil, err
+	}
+
+	if dir != "" {
+		files, err := filepath.Glob(filepath.Join(dir, "*.html"))
+		if err != nil {
+			return nil, err
+		}
+		if len(files) > 0 {
+			if tmpl, err = tmpl.ParseFiles(files...); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &Renderer{tmpl: tmpl}, nil
+}
+
+func (r *Renderer) Render(w http.ResponseWriter, status int, name string, data any) error {
+	var buf bytes.Buffer
+	if err := r.tmpl.ExecuteTemplate(&buf, name+".html", data); err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
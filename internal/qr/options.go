@@ -0,0 +1,135 @@
+package qr
+
+import (
+	"fmt"
+	"image/color"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+type Format string
+
+const (
+	FormatPNG Format = "png"
+	FormatSVG Format = "svg"
+)
+
+const (
+	defaultSize   = 256
+	minSize       = 64
+	maxSize       = 2048
+	defaultMargin = 4
+	maxMargin     = 16
+)
+
+// Options control how a QR code is rendered. Size is the image width in
+// pixels, Margin the quiet zone in modules.
+type Options struct {
+	Format Format
+	Size   int
+	Margin int
+	ECC    string
+	FG     color.RGBA
+	BG     color.RGBA
+	Logo   bool
+}
+
+// ParseOptions reads ?format=&size=&margin=&ecc=&fg=&bg=&logo= with defaults
+// for everything missing.
+func ParseOptions(q url.Values) (Options, error) {
+	opts := Options{
+		Format: FormatPNG,
+		Size:   defaultSize,
+		Margin: defaultMargin,
+		ECC:    "M",
+		FG:     color.RGBA{A: 0xff},
+		BG:     color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+
+	if v := q.Get("format"); v != "" {
+		opts.Format = Format(strings.ToLower(v))
+		if opts.Format != FormatPNG && opts.Format != FormatSVG {
+			return opts, fmt.Errorf("format must be png or svg")
+		}
+	}
+
+	if v := q.Get("size"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size < minSize || size > maxSize {
+			return opts, fmt.Errorf("size must be between %d and %d", minSize, maxSize)
+		}
+		opts.Size = size
+	}
+
+	if v := q.Get("margin"); v != "" {
+		margin, err := strconv.Atoi(v)
+		if err != nil || margin < 0 || margin > maxMargin {
+			return opts, fmt.Errorf("margin must be between 0 and %d", maxMargin)
+		}
+		opts.Margin = margin
+	}
+
+	if v := q.Get("ecc"); v != "" {
+		opts.ECC = strings.ToUpper(v)
+		if _, ok := recoveryLevels[opts.ECC]; !ok {
+			return opts, fmt.Errorf("ecc must be one of L, M, Q, H")
+		}
+	}
+
+	var err error
+	if v := q.Get("fg"); v != "" {
+		if opts.FG, err = parseHexColor(v); err != nil {
+			return opts, fmt.Errorf("fg: %w", err)
+		}
+	}
+	if v := q.Get("bg"); v != "" {
+		if opts.BG, err = parseHexColor(v); err != nil {
+			return opts, fmt.Errorf("bg: %w", err)
+		}
+	}
+
+	if v := q.Get("logo"); v != "" {
+		if opts.Logo, err = strconv.ParseBool(v); err != nil {
+			return opts, fmt.Errorf("logo must be true or false")
+		}
+	}
+
+	// a logo hides part of the symbol, keep enough redundancy to read it
+	if opts.Logo && opts.ECC != "H" {
+		opts.ECC = "Q"
+	}
+
+	return opts, nil
+}
+
+func (o Options) cacheKey(content string) string {
+	return fmt.Sprintf("%s|%s|%d|%d|%s|%x|%x|%t", content, o.Format, o.Size, o.Margin, o.ECC, o.FG, o.BG, o.Logo)
+}
+
+var recoveryLevels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// parseHexColor accepts RRGGBB or RRGGBBAA, with or without a leading #.
+func parseHexColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) != 6 && len(s) != 8 {
+		return color.RGBA{}, fmt.Errorf("color must be RRGGBB or RRGGBBAA hex")
+	}
+
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("color must be RRGGBB or RRGGBBAA hex")
+	}
+	if len(s) == 6 {
+		v = v<<8 | 0xff
+	}
+
+	return color.RGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
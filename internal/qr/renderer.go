@@ -0,0 +1,188 @@
+package qr
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+
+	"github.com/skip2/go-qrcode"
+)
+
+var ErrNoLogo = errors.New("no logo configured")
+
+// logoShare is the part of the QR width covered by the center logo.
+const logoShare = 0.22
+
+// Renderer draws QR codes for short URLs and caches the results.
+type Renderer struct {
+	logo    image.Image
+	logoPNG []byte
+	cache   *cache
+}
+
+// NewRenderer loads the optional center logo (PNG) and sets up a cache
+// of cacheSize rendered images.
+func NewRenderer(logoPath string, cacheSize int) (*Renderer, error) {
+	r := &Renderer{cache: newCache(cacheSize)}
+
+	if logoPath != "" {
+		data, err := os.ReadFile(logoPath)
+		if err != nil {
+			return nil, err
+		}
+		logo, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("logo must be a png: %w", err)
+		}
+		r.logo, r.logoPNG = logo, data
+	}
+
+	return r, nil
+}
+
+// Render returns the encoded image and its content type.
+func (r *Renderer) Render(content string, opts Options) ([]byte, string, error) {
+	if opts.Logo && r.logo == nil {
+		return nil, "", ErrNoLogo
+	}
+
+	contentType := "image/png"
+	if opts.Format == FormatSVG {
+		contentType = "image/svg+xml"
+	}
+
+	key := opts.cacheKey(content)
+	if body, ok := r.cache.get(key); ok {
+		return body, contentType, nil
+	}
+
+	code, err := qrcode.New(content, recoveryLevels[opts.ECC])
+	if err != nil {
+		return nil, "", err
+	}
+	code.DisableBorder = true
+	bitmap := code.Bitmap()
+
+	var body []byte
+	if opts.Format == FormatSVG {
+		body = r.svg(bitmap, opts)
+	} else if body, err = r.png(bitmap, opts); err != nil {
+		return nil, "", err
+	}
+
+	r.cache.add(key, body)
+
+	return body, contentType, nil
+}
+
+func (r *Renderer) png(bitmap [][]bool, opts Options) ([]byte, error) {
+	modules := len(bitmap) + 2*opts.Margin
+	scale := max(opts.Size/modules, 1)
+	// center the symbol when size is not a multiple of the module count
+	offset := (opts.Size - modules*scale) / 2
+
+	img := image.NewRGBA(image.Rect(0, 0, opts.Size, opts.Size))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: opts.BG}, image.Point{}, draw.Src)
+
+	fg := &image.Uniform{C: opts.FG}
+	for y, row := range bitmap {
+		for x, set := range row {
+			if !set {
+				continue
+			}
+			px := offset + (x+opts.Margin)*scale
+			py := offset + (y+opts.Margin)*scale
+			draw.Draw(img, image.Rect(px, py, px+scale, py+scale), fg, image.Point{}, draw.Src)
+		}
+	}
+
+	if opts.Logo {
+		r.drawLogo(img, opts, len(bitmap)*scale)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// drawLogo puts the logo scaled to logoShare of the symbol on a background
+// colored pad in the middle of the image.
+func (r *Renderer) drawLogo(img *image.RGBA, opts Options, symbolSize int) {
+	side := int(float64(symbolSize) * logoShare)
+	if side < 1 {
+		return
+	}
+
+	pad := side / 10
+	center := opts.Size / 2
+	padRect := image.Rect(center-side/2-pad, center-side/2-pad, center+side/2+pad, center+side/2+pad)
+	draw.Draw(img, padRect, &image.Uniform{C: opts.BG}, image.Point{}, draw.Src)
+
+	scaled := scaleNearest(r.logo, side)
+	dst := image.Rect(center-side/2, center-side/2, center-side/2+side, center-side/2+side)
+	draw.Draw(img, dst, scaled, image.Point{}, draw.Over)
+}
+
+func (r *Renderer) svg(bitmap [][]bool, opts Options) []byte {
+	modules := len(bitmap) + 2*opts.Margin
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		opts.Size, opts.Size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="100%%" height="100%%" fill="%s"/>`, svgColor(opts.BG))
+
+	fmt.Fprintf(&buf, `<path fill="%s" d="`, svgColor(opts.FG))
+	for y, row := range bitmap {
+		for x, set := range row {
+			if set {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x+opts.Margin, y+opts.Margin)
+			}
+		}
+	}
+	buf.WriteString(`"/>`)
+
+	if opts.Logo {
+		side := float64(len(bitmap)) * logoShare
+		pad := side / 10
+		pos := (float64(modules) - side) / 2
+		fmt.Fprintf(&buf, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="%s"/>`,
+			pos-pad, pos-pad, side+2*pad, side+2*pad, svgColor(opts.BG))
+		fmt.Fprintf(&buf, `<image x="%.2f" y="%.2f" width="%.2f" height="%.2f" href="data:image/png;base64,%s"/>`,
+			pos, pos, side, side, base64.StdEncoding.EncodeToString(r.logoPNG))
+	}
+
+	buf.WriteString(`</svg>`)
+
+	return buf.Bytes()
+}
+
+func svgColor(c color.RGBA) string {
+	if c.A == 0xff {
+		return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+	}
+
+	return fmt.Sprintf("rgba(%d,%d,%d,%.3f)", c.R, c.G, c.B, float64(c.A)/0xff)
+}
+
+func scaleNearest(src image.Image, side int) image.Image {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, side, side))
+	for y := range side {
+		for x := range side {
+			sx := b.Min.X + x*b.Dx()/side
+			sy := b.Min.Y + y*b.Dy()/side
+			dst.Set(x, y, src.At(sx, sy))
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"shorter/internal/auth"
+	"shorter/internal/metrics"
+	"shorter/internal/request"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// KeyFunc picks the identity a request is limited by.
+type KeyFunc func(r *http.Request) string
+
+func ByIP(r *http.Request) string {
+	return "ip:" + request.ClientIP(r)
+}
+
+// ByAPIKeyOrIP limits authenticated requests per key and anonymous ones per IP.
+func ByAPIKeyOrIP(r *http.Request) string {
+	if key := auth.KeyFromContext(r.Context()); key != nil {
+		return fmt.Sprintf("key:%d", key.ID)
+	}
+
+	return ByIP(r)
+}
+
+// Middleware enforces the policy and reports it with RateLimit-* headers.
+// Store errors let the request through, the limiter must not take the
+// service down.
+func Middleware(store Store, policy Policy, keyFunc KeyFunc, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !policy.Enabled() {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res, err := store.Take(r.Context(), keyFunc(r), policy)
+			if err != nil {
+				logger.Error("rate limit store error", zap.Error(err), zap.String("policy", policy.Name))
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", policy.Requests, int(policy.Period.Seconds()), res.Limit))
+			w.Header().Set("RateLimit-Limit", fmt.Sprint(res.Limit))
+			w.Header().Set("RateLimit-Remaining", fmt.Sprint(res.Remaining))
+			w.Header().Set("RateLimit-Reset", fmt.Sprint(ceilSeconds(res.Reset)))
+
+			if !res.Allowed {
+				metrics.RateLimitRejectedTotal.WithLabelValues(policy.Name).Inc()
+				w.Header().Set("Retry-After", fmt.Sprint(ceilSeconds(res.RetryAfter)))
+				http.Error(w, "too many requests", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
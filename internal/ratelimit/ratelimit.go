@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Policy describes a token bucket: Requests tokens are refilled every
+// Period and at most Burst can be spent at once.
+type Policy struct {
+	Name     string
+	Requests int
+	Period   time.Duration
+	Burst    int
+}
+
+func (p Policy) Enabled() bool {
+	return p.Requests > 0 && p.Period > 0
+}
+
+// rate returns refilled tokens per second.
+func (p Policy) rate() float64 {
+	return float64(p.Requests) / p.Period.Seconds()
+}
+
+func (p Policy) capacity() int {
+	if p.Burst > 0 {
+		return p.Burst
+	}
+
+	return p.Requests
+}
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // until the bucket is full again
+	RetryAfter time.Duration // until the next request is allowed
+}
+
+// Store keeps bucket state. The in-memory store works for a single
+// instance, a shared store (e.g. Redis) can implement the same interface.
+type Store interface {
+	Take(ctx context.Context, key string, policy Policy) (Result, error)
+}
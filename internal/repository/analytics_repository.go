@@ -3,13 +3,17 @@ package repository
 import (
 	"context"
 	"shorter/internal/enricher"
+	"shorter/internal/model"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type AnalyticsRepository interface {
 	Save(ctx context.Context, click *enricher.EnrichedClick) error
-	GetStats(ctx context.Context, alias string) (*Stats, error)
+	GetStats(ctx context.Context, workspaceID int64, link *model.Link) (*Stats, error)
+	CountClicks(ctx context.Context, q *model.ExportQuery) (int64, error)
+	StreamClicks(ctx context.Context, q *model.ExportQuery, fn func(*model.ClickRow) error) error
+	StreamAggregates(ctx context.Context, q *model.ExportQuery, fn func(*model.ClickAggregate) error) error
 }
 
 type PgAnalyticsRepository struct {
@@ -23,6 +27,7 @@ type Stats struct {
 	ByCountry   map[string]int `json:"by_country"`
 	ByDevice    map[string]int `json:"by_device"`
 	ByBrowser   map[string]int `json:"by_browser"`
+	ByVariant   map[string]int `json:"by_variant,omitempty"`
 }
 
 func NewAnalyticsRepository(db *pgxpool.Pool) *PgAnalyticsRepository {
@@ -34,11 +39,12 @@ func NewAnalyticsRepository(db *pgxpool.Pool) *PgAnalyticsRepository {
 func (r *PgAnalyticsRepository) Save(ctx context.Context, click *enricher.EnrichedClick) error {
 	q := `
 		INSERT INTO enriched_clicks
-		(alias, ip, country, city, device_type, os, browser, referer, timestamp)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		(link_id, alias, ip, country, city, device_type, os, browser, referer, timestamp, variant)
+		VALUES (NULLIF($1, 0), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 	`
 
 	_, err := r.db.Exec(ctx, q,
+		click.LinkID,
 		click.Alias,
 		click.IP,
 		click.Country,
@@ -48,14 +54,29 @@ func (r *PgAnalyticsRepository) Save(ctx context.Context, click *enricher.Enrich
 		click.Browser,
 		click.Referer,
 		click.Timestamp,
+		click.Variant,
 	)
 
 	return err
 }
 
-func (r *PgAnalyticsRepository) GetStats(ctx context.Context, alias string) (*Stats, error) {
+// GetStats returns nil if the link does not belong to the workspace.
+func (r *PgAnalyticsRepository) GetStats(ctx context.Context, workspaceID int64, link *model.Link) (*Stats, error) {
+	var inWorkspace bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM short_links WHERE id = $1 AND workspace_id = $2
+		)
+	`, link.ID, workspaceID).Scan(&inWorkspace)
+	if err != nil {
+		return nil, err
+	}
+	if !inWorkspace {
+		return nil, nil
+	}
+
 	var stats Stats
-	stats.Alias = alias
+	stats.Alias = link.Alias
 
 	q := `
 		SELECT 
@@ -63,9 +84,9 @@ func (r *PgAnalyticsRepository) GetStats(ctx context.Context, alias string) (*St
 		FROM 
 			enriched_clicks
 		WHERE 
-			alias=$1
+			link_id=$1
 	`
-	rows, err := r.db.Query(ctx, q, alias)
+	rows, err := r.db.Query(ctx, q, link.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -81,5 +102,134 @@ func (r *PgAnalyticsRepository) GetStats(ctx context.Context, alias string) (*St
 
 	stats.TotalClicks = int(rows.CommandTag().RowsAffected())
 
+	if len(link.Variants) > 0 {
+		if stats.ByVariant, err = r.countByVariant(ctx, link.ID); err != nil {
+			return nil, err
+		}
+	}
+
 	return &stats, nil
 }
+
+// countByVariant counts clicks of each A/B variant of the link.
+func (r *PgAnalyticsRepository) countByVariant(ctx context.Context, linkID int64) (map[string]int, error) {
+	q := `
+		SELECT
+			variant, COUNT(*)
+		FROM
+			enriched_clicks
+		WHERE
+			link_id = $1 AND variant IS NOT NULL
+		GROUP BY variant
+	`
+	rows, err := r.db.Query(ctx, q, linkID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byVariant := make(map[string]int)
+	for rows.Next() {
+		var variant string
+		var count int
+		if err := rows.Scan(&variant, &count); err != nil {
+			return nil, err
+		}
+		byVariant[variant] = count
+	}
+
+	return byVariant, rows.Err()
+}
+
+// CountClicks returns the number of raw clicks the export query covers.
+func (r *PgAnalyticsRepository) CountClicks(ctx context.Context, q *model.ExportQuery) (int64, error) {
+	var n int64
+	err := r.db.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM
+			enriched_clicks
+		WHERE link_id = $1
+			AND ($2::timestamptz IS NULL OR timestamp >= $2)
+			AND ($3::timestamptz IS NULL OR timestamp < $3)
+	`, q.LinkID, q.From, q.To).Scan(&n)
+
+	return n, err
+}
+
+// StreamClicks calls fn for every click of the export query, oldest
+// first. Rows are read from the connection as fn consumes them, so the
+// result set is never held in memory.
+func (r *PgAnalyticsRepository) StreamClicks(ctx context.Context, q *model.ExportQuery, fn func(*model.ClickRow) error) error {
+	rows, err := r.db.Query(ctx, `
+		SELECT
+			timestamp, alias, ip, country, city, device_type, os, browser, referer, variant
+		FROM
+			enriched_clicks
+		WHERE link_id = $1
+			AND ($2::timestamptz IS NULL OR timestamp >= $2)
+			AND ($3::timestamptz IS NULL OR timestamp < $3)
+		ORDER BY timestamp, id
+	`, q.LinkID, q.From, q.To)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var row model.ClickRow
+	for rows.Next() {
+		err := rows.Scan(
+			&row.Timestamp,
+			&row.Alias,
+			&row.IP,
+			&row.Country,
+			&row.City,
+			&row.Device,
+			&row.OS,
+			&row.Browser,
+			&row.Referer,
+			&row.Variant,
+		)
+		if err != nil {
+			return err
+		}
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// StreamAggregates calls fn for every period, country and device of the
+// export query in period order. Periods are truncated in UTC.
+func (r *PgAnalyticsRepository) StreamAggregates(ctx context.Context, q *model.ExportQuery, fn func(*model.ClickAggregate) error) error {
+	rows, err := r.db.Query(ctx, `
+		SELECT
+			date_trunc($4, enriched_clicks.timestamp AT TIME ZONE 'UTC') AS period,
+			country, device_type, COUNT(*), COUNT(DISTINCT ip)
+		FROM
+			enriched_clicks
+		WHERE link_id = $1
+			AND ($2::timestamptz IS NULL OR timestamp >= $2)
+			AND ($3::timestamptz IS NULL OR timestamp < $3)
+		GROUP BY period, country, device_type
+		ORDER BY period, country, device_type
+	`, q.LinkID, q.From, q.To, q.Aggregate)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var row model.ClickAggregate
+	for rows.Next() {
+		if err := rows.Scan(&row.Period, &row.Country, &row.Device, &row.Clicks, &row.UniqueIPs); err != nil {
+			return err
+		}
+		row.Period = row.Period.UTC()
+		if err := fn(&row); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
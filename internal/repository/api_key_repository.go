@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"shorter/internal/model"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *model.APIKey, hash string) error
+	GetActiveByHash(ctx context.Context, hash string) (*model.APIKey, error)
+	TouchLastUsed(ctx context.Context, id int64) error
+}
+
+type PgAPIKeyRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewAPIKeyRepository(db *pgxpool.Pool) *PgAPIKeyRepository {
+	return &PgAPIKeyRepository{db: db}
+}
+
+func (r *PgAPIKeyRepository) Create(ctx context.Context, key *model.APIKey, hash string) error {
+	q := `
+		INSERT INTO
+			api_keys (name, key_prefix, key_hash)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(ctx, q, key.Name, key.Prefix, hash).Scan(&key.ID, &key.CreatedAt)
+}
+
+// GetActiveByHash looks up a key that has not been revoked.
+func (r *PgAPIKeyRepository) GetActiveByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	q := `
+		SELECT
+			id, name, key_prefix, created_at, last_used_at, revoked_at
+		FROM
+			api_keys
+		WHERE key_hash = $1 AND revoked_at IS NULL
+	`
+	var key model.APIKey
+	err := r.db.QueryRow(ctx, q, hash).Scan(
+		&key.ID,
+		&key.Name,
+		&key.Prefix,
+		&key.CreatedAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func (r *PgAPIKeyRepository) TouchLastUsed(ctx context.Context, id int64) error {
+	q := `
+		UPDATE api_keys
+		SET last_used_at = NOW()
+		WHERE id = $1
+	`
+	_, err := r.db.Exec(ctx, q, id)
+	return err
+}
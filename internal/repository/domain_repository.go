@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"shorter/internal/model"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type DomainRepository interface {
+	Create(ctx context.Context, domain *model.Domain) error
+	GetByHostname(ctx context.Context, hostname string) (*model.Domain, error)
+	GetByID(ctx context.Context, id int64) (*model.Domain, error)
+	ListForWorkspace(ctx context.Context, workspaceID int64) ([]model.Domain, error)
+}
+
+type PgDomainRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewDomainRepository(db *pgxpool.Pool) *PgDomainRepository {
+	return &PgDomainRepository{db: db}
+}
+
+func (r *PgDomainRepository) Create(ctx context.Context, domain *model.Domain) error {
+	q := `
+		INSERT INTO
+			domains (hostname, workspace_id, fallback_url, not_found_url)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	return r.db.QueryRow(ctx, q,
+		domain.Hostname,
+		domain.WorkspaceID,
+		domain.FallbackUrl,
+		domain.NotFoundUrl,
+	).Scan(&domain.ID, &domain.CreatedAt)
+}
+
+func (r *PgDomainRepository) GetByHostname(ctx context.Context, hostname string) (*model.Domain, error) {
+	q := `
+		SELECT ` + domainColumns + `
+		FROM
+			domains
+		WHERE hostname = $1
+	`
+	domain, err := scanDomain(r.db.QueryRow(ctx, q, hostname))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+
+	return domain, err
+}
+
+func (r *PgDomainRepository) GetByID(ctx context.Context, id int64) (*model.Domain, error) {
+	q := `
+		SELECT ` + domainColumns + `
+		FROM
+			domains
+		WHERE id = $1
+	`
+	domain, err := scanDomain(r.db.QueryRow(ctx, q, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+
+	return domain, err
+}
+
+// ListForWorkspace returns domains owned by the workspace and shared ones.
+func (r *PgDomainRepository) ListForWorkspace(ctx context.Context, workspaceID int64) ([]model.Domain, error) {
+	q := `
+		SELECT ` + domainColumns + `
+		FROM
+			domains
+		WHERE workspace_id = $1 OR workspace_id IS NULL
+		ORDER BY hostname
+	`
+	rows, err := r.db.Query(ctx, q, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	domains := []model.Domain{}
+	for rows.Next() {
+		domain, err := scanDomain(rows)
+		if err != nil {
+			return nil, err
+		}
+		domains = append(domains, *domain)
+	}
+
+	return domains, rows.Err()
+}
+
+const domainColumns = `
+	id, hostname, workspace_id, fallback_url, not_found_url, created_at
+`
+
+func scanDomain(row pgx.Row) (*model.Domain, error) {
+	var domain model.Domain
+	err := row.Scan(
+		&domain.ID,
+		&domain.Hostname,
+		&domain.WorkspaceID,
+		&domain.FallbackUrl,
+		&domain.NotFoundUrl,
+		&domain.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain, nil
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"shorter/internal/model"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type ExportRepository interface {
+	Create(ctx context.Context, job *model.ExportJob) error
+	Get(ctx context.Context, workspaceID, id int64) (*model.ExportJob, error)
+	Claim(ctx context.Context, staleBefore time.Time) (*model.ExportJob, error)
+	Finish(ctx context.Context, id int64, path string, rows int64, expiresAt time.Time) error
+	Fail(ctx context.Context, id int64, reason string, expiresAt time.Time) error
+	DeleteExpired(ctx context.Context) ([]string, error)
+}
+
+type PgExportRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewExportRepository(db *pgxpool.Pool) *PgExportRepository {
+	return &PgExportRepository{db: db}
+}
+
+func (r *PgExportRepository) Create(ctx context.Context, job *model.ExportJob) error {
+	q := `
+		INSERT INTO
+			export_jobs (workspace_id, link_id, alias, format, aggregate, from_time, to_time)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7)
+		RETURNING id, status, created_at
+	`
+	return r.db.QueryRow(ctx, q,
+		job.WorkspaceID,
+		job.LinkID,
+		job.Alias,
+		job.Format,
+		job.Aggregate,
+		job.From,
+		job.To,
+	).Scan(&job.ID, &job.Status, &job.CreatedAt)
+}
+
+func (r *PgExportRepository) Get(ctx context.Context, workspaceID, id int64) (*model.ExportJob, error) {
+	q := `
+		SELECT ` + exportJobColumns + `
+		FROM
+			export_jobs
+		WHERE id = $1 AND workspace_id = $2
+	`
+	job, err := scanExportJob(r.db.QueryRow(ctx, q, id, workspaceID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+
+	return job, err
+}
+
+// Claim marks the oldest pending job as running and returns it, nil when
+// there is none. Jobs left running since before staleBefore belonged to a
+// worker that died and are claimed again.
+func (r *PgExportRepository) Claim(ctx context.Context, staleBefore time.Time) (*model.ExportJob, error) {
+	q := `
+		UPDATE export_jobs
+		SET status = 'running', started_at = NOW()
+		WHERE id = (
+			SELECT id
+			FROM
+				export_jobs
+			WHERE status = 'pending' OR (status = 'running' AND started_at < $1)
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + exportJobColumns + `
+	`
+	job, err := scanExportJob(r.db.QueryRow(ctx, q, staleBefore))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+
+	return job, err
+}
+
+func (r *PgExportRepository) Finish(ctx context.Context, id int64, path string, rows int64, expiresAt time.Time) error {
+	q := `
+		UPDATE export_jobs
+		SET status = 'done', file_path = $2, row_count = $3, expires_at = $4, finished_at = NOW()
+		WHERE id = $1
+	`
+	_, err := r.db.Exec(ctx, q, id, path, rows, expiresAt)
+
+	return err
+}
+
+func (r *PgExportRepository) Fail(ctx context.Context, id int64, reason string, expiresAt time.Time) error {
+	q := `
+		UPDATE export_jobs
+		SET status = 'failed', error = $2, expires_at = $3, finished_at = NOW()
+		WHERE id = $1
+	`
+	_, err := r.db.Exec(ctx, q, id, reason, expiresAt)
+
+	return err
+}
+
+// DeleteExpired removes jobs past their expiry and returns the paths of
+// their files.
+func (r *PgExportRepository) DeleteExpired(ctx context.Context) ([]string, error) {
+	rows, err := r.db.Query(ctx, `
+		DELETE FROM export_jobs
+		WHERE expires_at < NOW()
+		RETURNING file_path
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path *string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+		if path != nil {
+			paths = append(paths, *path)
+		}
+	}
+
+	return paths, rows.Err()
+}
+
+const exportJobColumns = `
+	id, workspace_id, link_id, alias, format, COALESCE(aggregate, ''), from_time, to_time,
+	status, file_path, row_count, error, created_at, finished_at, expires_at
+`
+
+func scanExportJob(row pgx.Row) (*model.ExportJob, error) {
+	var job model.ExportJob
+	err := row.Scan(
+		&job.ID,
+		&job.WorkspaceID,
+		&job.LinkID,
+		&job.Alias,
+		&job.Format,
+		&job.Aggregate,
+		&job.From,
+		&job.To,
+		&job.Status,
+		&job.FilePath,
+		&job.Rows,
+		&job.Error,
+		&job.CreatedAt,
+		&job.FinishedAt,
+		&job.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &job, nil
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"shorter/internal/model"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type HealthRepository interface {
+	DueLinks(ctx context.Context, checkedBefore time.Time, limit int) ([]model.HealthTarget, error)
+	Save(ctx context.Context, health *model.LinkHealth, ok bool, threshold int) error
+	GetByLinkID(ctx context.Context, linkID int64) (*model.LinkHealth, error)
+	CountBroken(ctx context.Context) (int, error)
+}
+
+type PgHealthRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewHealthRepository(db *pgxpool.Pool) *PgHealthRepository {
+	return &PgHealthRepository{db: db}
+}
+
+// DueLinks returns active links never checked or last checked before
+// checkedBefore, oldest first.
+func (r *PgHealthRepository) DueLinks(ctx context.Context, checkedBefore time.Time, limit int) ([]model.HealthTarget, error) {
+	q := `
+		SELECT
+			sl.id, sl.original_url
+		FROM
+			short_links sl
+			LEFT JOIN link_health lh ON lh.link_id = sl.id
+		WHERE sl.is_active
+			AND (sl.expires_at IS NULL OR sl.expires_at > NOW())
+			AND (lh.checked_at IS NULL OR lh.checked_at < $1)
+		ORDER BY lh.checked_at NULLS FIRST
+		LIMIT $2
+	`
+	rows, err := r.db.Query(ctx, q, checkedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var targets []model.HealthTarget
+	for rows.Next() {
+		var t model.HealthTarget
+		if err := rows.Scan(&t.LinkID, &t.Url); err != nil {
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+
+	return targets, rows.Err()
+}
+
+// Save records a probe. Failures in a row are counted in the database, the
+// link is broken once they reach threshold and healthy again after one
+// successful probe. The counters are written back to health.
+func (r *PgHealthRepository) Save(ctx context.Context, health *model.LinkHealth, ok bool, threshold int) error {
+	q := `
+		INSERT INTO link_health (
+			link_id, checked_at, status_code, latency_ms, final_url, tls_expires_at, error,
+			consecutive_failures, broken, broken_since
+		)
+		VALUES (
+			$1, $2, $3, $4, $5, $6, $7,
+			CASE WHEN $8 THEN 0 ELSE 1 END,
+			NOT $8 AND 1 >= $9,
+			CASE WHEN NOT $8 AND 1 >= $9 THEN $2 END
+		)
+		ON CONFLICT (link_id) DO UPDATE SET
+			checked_at = EXCLUDED.checked_at,
+			status_code = EXCLUDED.status_code,
+			latency_ms = EXCLUDED.latency_ms,
+			final_url = EXCLUDED.final_url,
+			tls_expires_at = EXCLUDED.tls_expires_at,
+			error = EXCLUDED.error,
+			consecutive_failures = CASE WHEN $8 THEN 0 ELSE link_health.consecutive_failures + 1 END,
+			broken = NOT $8 AND link_health.consecutive_failures + 1 >= $9,
+			broken_since = CASE
+				WHEN $8 THEN NULL
+				WHEN link_health.consecutive_failures + 1 >= $9 THEN COALESCE(link_health.broken_since, $2)
+				ELSE link_health.broken_since
+			END
+		RETURNING consecutive_failures, broken, broken_since
+	`
+	return r.db.QueryRow(ctx, q,
+		health.LinkID,
+		health.CheckedAt,
+		health.StatusCode,
+		health.LatencyMs,
+		health.FinalUrl,
+		health.TLSExpiresAt,
+		health.Error,
+		ok,
+		threshold,
+	).Scan(&health.ConsecutiveFailures, &health.Broken, &health.BrokenSince)
+}
+
+func (r *PgHealthRepository) GetByLinkID(ctx context.Context, linkID int64) (*model.LinkHealth, error) {
+	q := `
+		SELECT
+			link_id, checked_at, status_code, latency_ms, final_url, tls_expires_at, error,
+			consecutive_failures, broken, broken_since
+		FROM
+			link_health
+		WHERE link_id = $1
+	`
+	var h model.LinkHealth
+	err := r.db.QueryRow(ctx, q, linkID).Scan(
+		&h.LinkID,
+		&h.CheckedAt,
+		&h.StatusCode,
+		&h.LatencyMs,
+		&h.FinalUrl,
+		&h.TLSExpiresAt,
+		&h.Error,
+		&h.ConsecutiveFailures,
+		&h.Broken,
+		&h.BrokenSince,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &h, nil
+}
+
+func (r *PgHealthRepository) CountBroken(ctx context.Context) (int, error) {
+	var n int
+	err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM link_health WHERE broken`).Scan(&n)
+
+	return n, err
+}
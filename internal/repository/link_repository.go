@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"shorter/internal/model"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -11,62 +12,342 @@ import (
 
 type LinkRepository interface {
 	Create(ctx context.Context, link *model.Link) error
-	GetByAlias(ctx context.Context, alias string) (*model.Link, error)
-	IncClickCount(ctx context.Context, alias string) error
+	GetByAlias(ctx context.Context, domainID *int64, alias string) (*model.Link, error)
+	GetByAliasInWorkspace(ctx context.Context, workspaceID int64, domainID *int64, alias string) (*model.Link, error)
+	IncClickCount(ctx context.Context, id int64) (int, bool, error)
+	FindReusable(ctx context.Context, ownerID, workspaceID int64, domainID *int64, urlHash string) (*model.Link, error)
+	TakenAliases(ctx context.Context, domainID *int64, aliases []string) (map[string]bool, error)
+	NextAliasSequence(ctx context.Context) (int64, error)
+	SetActive(ctx context.Context, workspaceID int64, link *model.Link, active bool, changedBy, reason string) (bool, error)
+	ClaimExpired(ctx context.Context, limit int) ([]model.Link, error)
 }
 
 type PgLinkRepository struct {
 	db *pgxpool.Pool
+	// "Promo" also opens "promo"
+	caseInsensitive bool
 }
 
-func NewLinkRepository(db *pgxpool.Pool) *PgLinkRepository {
+func NewLinkRepository(db *pgxpool.Pool, caseInsensitive bool) *PgLinkRepository {
 
-	return &PgLinkRepository{db: db}
+	return &PgLinkRepository{db: db, caseInsensitive: caseInsensitive}
 }
 
 func (r *PgLinkRepository) Create(ctx context.Context, link *model.Link) error {
 	q := `
 		INSERT INTO 
-			short_links (alias, original_url, expires_at)
-		VALUES ($1, $2, $3)
+			short_links (
+				alias, original_url, active_from, scheduled_redirect_url,
+				expires_at, expired_redirect_url, password_hash, max_clicks,
+				owner_id, workspace_id, domain_id, alias_key, url_hash,
+				geo_rules, device_rules, variants, sticky_variants, routing_rules,
+				preview, meta, pixels, pixel_delay_ms
+			)
+		VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18,
+			$19, $20, $21, $22
+		)
+		RETURNING id, created_at
 	`
-	_, err := r.db.Exec(ctx, q,
+	return r.db.QueryRow(ctx, q,
 		link.Alias,
 		link.OriginalUrl,
+		link.ActiveFrom,
+		link.ScheduledRedirectUrl,
 		link.ExpiresAt,
-	)
+		link.ExpiredRedirectUrl,
+		link.PasswordHash,
+		link.MaxClicks,
+		link.OwnerID,
+		link.WorkspaceID,
+		link.DomainID,
+		aliasKey(link.Alias),
+		link.UrlHash,
+		jsonOrNull(link.GeoRules),
+		jsonOrNull(link.DeviceRules),
+		jsonOrNull(link.Variants),
+		link.StickyVariants,
+		jsonOrNull(link.RoutingRules),
+		link.Preview,
+		link.Meta,
+		jsonOrNull(link.Pixels),
+		link.PixelDelayMs,
+	).Scan(&link.ID, &link.CreatedAt)
+}
 
-	return err
+// GetByAlias finds the link by alias on a domain, nil domainID means the
+// default host.
+func (r *PgLinkRepository) GetByAlias(ctx context.Context, domainID *int64, alias string) (*model.Link, error) {
+	q := `
+		SELECT ` + linkColumns + `
+		FROM
+			short_links
+		WHERE (alias_key = $1 OR alias = $2) AND domain_id IS NOT DISTINCT FROM $3
+		ORDER BY alias = $2 DESC
+		LIMIT 1
+	`
+	link, err := scanLink(r.db.QueryRow(ctx, q, r.lookupKey(alias), alias, domainID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+
+	return link, err
 }
 
-func (r *PgLinkRepository) GetByAlias(ctx context.Context, alias string) (*model.Link, error) {
+// GetByAliasInWorkspace is GetByAlias limited to links of one workspace,
+// used by the API so teams never see each other's links.
+func (r *PgLinkRepository) GetByAliasInWorkspace(ctx context.Context, workspaceID int64, domainID *int64, alias string) (*model.Link, error) {
 	q := `
-		SELECT 
-			alias, original_url, expires_at, click_count
+		SELECT ` + linkColumns + `
 		FROM
 			short_links
-		WHERE alias = $1
+		WHERE (alias_key = $1 OR alias = $2) AND domain_id IS NOT DISTINCT FROM $3 AND workspace_id = $4
+		ORDER BY alias = $2 DESC
+		LIMIT 1
 	`
-	var link model.Link
-	err := r.db.QueryRow(ctx, q, alias).Scan(
-		&link.Alias,
-		&link.OriginalUrl,
-		&link.ExpiresAt,
-		&link.ClickCount,
-	)
+	link, err := scanLink(r.db.QueryRow(ctx, q, r.lookupKey(alias), alias, domainID, workspaceID))
 	if err == pgx.ErrNoRows {
 		return nil, nil
 	}
 
-	return &link, err
+	return link, err
 }
 
-func (r *PgLinkRepository) IncClickCount(ctx context.Context, alias string) error {
+// IncClickCount atomically increments the click counter and returns its
+// new value. It returns false when the link has already reached
+// max_clicks, so concurrent requests can never push the counter past the
+// limit.
+func (r *PgLinkRepository) IncClickCount(ctx context.Context, id int64) (int, bool, error) {
 	q := `
 		UPDATE short_links
 		SET click_count = click_count + 1
-		WHERE alias = $1
+		WHERE id = $1
+			AND (max_clicks IS NULL OR click_count < max_clicks)
+		RETURNING click_count
+	`
+	var count int
+	err := r.db.QueryRow(ctx, q, id).Scan(&count)
+	if err == pgx.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	return count, true, nil
+}
+
+// FindReusable returns the newest plain link of the owner pointing at the
+// same normalized URL that still redirects.
+func (r *PgLinkRepository) FindReusable(ctx context.Context, ownerID, workspaceID int64, domainID *int64, urlHash string) (*model.Link, error) {
+	q := `
+		SELECT ` + linkColumns + `
+		FROM
+			short_links
+		WHERE owner_id = $1 AND url_hash = $2
+			AND workspace_id = $3 AND domain_id IS NOT DISTINCT FROM $4
+			AND is_active
+			AND active_from IS NULL AND expires_at IS NULL
+			AND max_clicks IS NULL AND password_hash IS NULL
+			AND geo_rules IS NULL AND device_rules IS NULL AND variants IS NULL
+			AND routing_rules IS NULL
+			AND NOT preview AND meta IS NULL AND pixels IS NULL
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	link, err := scanLink(r.db.QueryRow(ctx, q, ownerID, urlHash, workspaceID, domainID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+
+	return link, err
+}
+
+// TakenAliases reports which of the aliases are already used on the domain.
+// Aliases differing only in case are taken in both modes, the unique index
+// is on the lowercased key.
+func (r *PgLinkRepository) TakenAliases(ctx context.Context, domainID *int64, aliases []string) (map[string]bool, error) {
+	keys := make([]string, len(aliases))
+	for i, alias := range aliases {
+		keys[i] = aliasKey(alias)
+	}
+
+	q := `
+		SELECT alias, alias_key
+		FROM
+			short_links
+		WHERE (alias_key = ANY($1) OR alias = ANY($2)) AND domain_id IS NOT DISTINCT FROM $3
+	`
+	rows, err := r.db.Query(ctx, q, keys, aliases, domainID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	used := make(map[string]bool)
+	for rows.Next() {
+		var alias, key string
+		if err := rows.Scan(&alias, &key); err != nil {
+			return nil, err
+		}
+		used[alias] = true
+		used[key] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	taken := make(map[string]bool)
+	for i, alias := range aliases {
+		if used[alias] || used[keys[i]] {
+			taken[alias] = true
+		}
+	}
+
+	return taken, nil
+}
+
+// NextAliasSequence returns the next counter value for sequence based
+// alias generators.
+func (r *PgLinkRepository) NextAliasSequence(ctx context.Context) (int64, error) {
+	var n int64
+	err := r.db.QueryRow(ctx, `SELECT nextval('short_link_alias_seq')`).Scan(&n)
+
+	return n, err
+}
+
+// SetActive enables or disables a link and records who did it and why.
+// It returns false if the link does not exist in the workspace.
+func (r *PgLinkRepository) SetActive(ctx context.Context, workspaceID int64, link *model.Link, active bool, changedBy, reason string) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE short_links
+		SET is_active = $3
+		WHERE id = $1 AND workspace_id = $2
+	`, link.ID, workspaceID, active)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO
+			link_state_changes (link_id, alias, is_active, changed_by, reason)
+		VALUES ($1, $2, $3, $4, $5)
+	`, link.ID, link.Alias, active, changedBy, reason)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// ClaimExpired marks links whose expiry has passed as announced and returns
+// them, so each expiry is reported exactly once across instances.
+func (r *PgLinkRepository) ClaimExpired(ctx context.Context, limit int) ([]model.Link, error) {
+	q := `
+		UPDATE short_links
+		SET expiry_notified_at = NOW()
+		WHERE id IN (
+			SELECT id
+			FROM
+				short_links
+			WHERE expires_at <= NOW() AND expiry_notified_at IS NULL
+			ORDER BY expires_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + linkColumns + `
 	`
-	_, err := r.db.Exec(ctx, q, alias)
-	return err
+	rows, err := r.db.Query(ctx, q, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []model.Link
+	for rows.Next() {
+		link, err := scanLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, *link)
+	}
+
+	return links, rows.Err()
+}
+
+// jsonOrNull stores empty rule lists as NULL instead of a JSON null.
+func jsonOrNull[T any](rules []T) any {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	return rules
+}
+
+// aliasKey is the unique form of an alias stored in alias_key.
+func aliasKey(alias string) string {
+	return strings.ToLower(alias)
+}
+
+// lookupKey is matched against alias_key when opening a link, nil leaves
+// only the exact alias match.
+func (r *PgLinkRepository) lookupKey(alias string) *string {
+	if !r.caseInsensitive {
+		return nil
+	}
+
+	key := aliasKey(alias)
+	return &key
+}
+
+const linkColumns = `
+	id, alias, original_url, created_at, active_from, scheduled_redirect_url,
+	expires_at, expired_redirect_url, click_count, password_hash, max_clicks,
+	is_active, owner_id, workspace_id, domain_id, geo_rules,
+	device_rules, variants, sticky_variants, routing_rules, preview, meta,
+	pixels, pixel_delay_ms
+`
+
+func scanLink(row pgx.Row) (*model.Link, error) {
+	var link model.Link
+	err := row.Scan(
+		&link.ID,
+		&link.Alias,
+		&link.OriginalUrl,
+		&link.CreatedAt,
+		&link.ActiveFrom,
+		&link.ScheduledRedirectUrl,
+		&link.ExpiresAt,
+		&link.ExpiredRedirectUrl,
+		&link.ClickCount,
+		&link.PasswordHash,
+		&link.MaxClicks,
+		&link.IsActive,
+		&link.OwnerID,
+		&link.WorkspaceID,
+		&link.DomainID,
+		&link.GeoRules,
+		&link.DeviceRules,
+		&link.Variants,
+		&link.StickyVariants,
+		&link.RoutingRules,
+		&link.Preview,
+		&link.Meta,
+		&link.Pixels,
+		&link.PixelDelayMs,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &link, nil
 }
@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"context"
+	"shorter/internal/model"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WebhookRepository interface {
+	Create(ctx context.Context, hook *model.Webhook) error
+	List(ctx context.Context, workspaceID int64) ([]model.Webhook, error)
+	Get(ctx context.Context, workspaceID, id int64) (*model.Webhook, error)
+	Delete(ctx context.Context, workspaceID, id int64) (bool, error)
+	Enqueue(ctx context.Context, workspaceID int64, event string, payload []byte, clicks *int) (int64, error)
+	ClaimDue(ctx context.Context, limit int, leaseUntil time.Time) ([]model.WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, id int64, status int) error
+	MarkFailed(ctx context.Context, id int64, status *int, reason string, retryAt *time.Time) error
+	Deliveries(ctx context.Context, webhookID int64, status string, limit int) ([]model.WebhookDelivery, error)
+	Replay(ctx context.Context, webhookID, deliveryID int64) (*model.WebhookDelivery, error)
+}
+
+type PgWebhookRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWebhookRepository(db *pgxpool.Pool) *PgWebhookRepository {
+	return &PgWebhookRepository{db: db}
+}
+
+func (r *PgWebhookRepository) Create(ctx context.Context, hook *model.Webhook) error {
+	q := `
+		INSERT INTO
+			webhooks (workspace_id, url, secret, events, click_thresholds)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	thresholds := hook.ClickThresholds
+	if thresholds == nil {
+		thresholds = []int{}
+	}
+
+	return r.db.QueryRow(ctx, q,
+		hook.WorkspaceID,
+		hook.Url,
+		hook.Secret,
+		hook.Events,
+		thresholds,
+	).Scan(&hook.ID, &hook.CreatedAt)
+}
+
+// List returns webhooks of the workspace without their secrets.
+func (r *PgWebhookRepository) List(ctx context.Context, workspaceID int64) ([]model.Webhook, error) {
+	q := `
+		SELECT ` + webhookColumns + `
+		FROM
+			webhooks
+		WHERE workspace_id = $1
+		ORDER BY id
+	`
+	rows, err := r.db.Query(ctx, q, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := []model.Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, *hook)
+	}
+
+	return hooks, rows.Err()
+}
+
+func (r *PgWebhookRepository) Get(ctx context.Context, workspaceID, id int64) (*model.Webhook, error) {
+	q := `
+		SELECT ` + webhookColumns + `
+		FROM
+			webhooks
+		WHERE id = $1 AND workspace_id = $2
+	`
+	hook, err := scanWebhook(r.db.QueryRow(ctx, q, id, workspaceID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+
+	return hook, err
+}
+
+// Delete removes the webhook along with its delivery log.
+func (r *PgWebhookRepository) Delete(ctx context.Context, workspaceID, id int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM webhooks WHERE id = $1 AND workspace_id = $2`, id, workspaceID)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// Enqueue queues the event for every webhook of the workspace subscribed
+// to it. With clicks set only webhooks having that click threshold get it.
+// It returns the number of deliveries queued.
+func (r *PgWebhookRepository) Enqueue(ctx context.Context, workspaceID int64, event string, payload []byte, clicks *int) (int64, error) {
+	q := `
+		INSERT INTO
+			webhook_deliveries (webhook_id, event, payload)
+		SELECT id, $2::text, $3::jsonb
+		FROM
+			webhooks
+		WHERE workspace_id = $1
+			AND $2::text = ANY(events)
+			AND ($4::int IS NULL OR $4 = ANY(click_thresholds))
+	`
+	tag, err := r.db.Exec(ctx, q, workspaceID, event, payload, clicks)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// ClaimDue picks pending deliveries whose time has come and hides them
+// from other workers until leaseUntil, counting the attempt. Deliveries of
+// a worker that died mid-attempt are picked up again after the lease.
+func (r *PgWebhookRepository) ClaimDue(ctx context.Context, limit int, leaseUntil time.Time) ([]model.WebhookDelivery, error) {
+	q := `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = $2, attempts = d.attempts + 1
+		FROM
+			webhooks w
+		WHERE w.id = d.webhook_id
+			AND d.id IN (
+				SELECT id
+				FROM
+					webhook_deliveries
+				WHERE status = 'pending' AND next_attempt_at <= NOW()
+				ORDER BY next_attempt_at
+				LIMIT $1
+				FOR UPDATE SKIP LOCKED
+			)
+		RETURNING ` + deliveryColumns + `, w.url, w.secret
+	`
+	rows, err := r.db.Query(ctx, q, limit, leaseUntil)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []model.WebhookDelivery
+	for rows.Next() {
+		var d model.WebhookDelivery
+		if err := rows.Scan(append(deliveryFields(&d), &d.Url, &d.Secret)...); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+func (r *PgWebhookRepository) MarkDelivered(ctx context.Context, id int64, status int) error {
+	q := `
+		UPDATE webhook_deliveries
+		SET status = 'delivered', response_status = $2, error = NULL, delivered_at = NOW()
+		WHERE id = $1
+	`
+	_, err := r.db.Exec(ctx, q, id, status)
+
+	return err
+}
+
+// MarkFailed records a failed attempt. The delivery is retried at retryAt,
+// or given up on when retryAt is nil.
+func (r *PgWebhookRepository) MarkFailed(ctx context.Context, id int64, status *int, reason string, retryAt *time.Time) error {
+	q := `
+		UPDATE webhook_deliveries
+		SET status = CASE WHEN $4::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+			response_status = $2,
+			error = $3,
+			next_attempt_at = COALESCE($4, next_attempt_at)
+		WHERE id = $1
+	`
+	_, err := r.db.Exec(ctx, q, id, status, reason, retryAt)
+
+	return err
+}
+
+// Deliveries returns the delivery log of a webhook, newest first,
+// optionally filtered by status.
+func (r *PgWebhookRepository) Deliveries(ctx context.Context, webhookID int64, status string, limit int) ([]model.WebhookDelivery, error) {
+	q := `
+		SELECT ` + deliveryColumns + `
+		FROM
+			webhook_deliveries d
+		WHERE d.webhook_id = $1
+			AND ($2::text = '' OR d.status = $2)
+		ORDER BY d.id DESC
+		LIMIT $3
+	`
+	rows, err := r.db.Query(ctx, q, webhookID, status, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []model.WebhookDelivery{}
+	for rows.Next() {
+		var d model.WebhookDelivery
+		if err := rows.Scan(deliveryFields(&d)...); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// Replay queues a copy of a past delivery, leaving the original in the log.
+// It returns nil if the delivery does not belong to the webhook.
+func (r *PgWebhookRepository) Replay(ctx context.Context, webhookID, deliveryID int64) (*model.WebhookDelivery, error) {
+	q := `
+		INSERT INTO
+			webhook_deliveries AS d (webhook_id, event, payload, replay_of)
+		SELECT webhook_id, event, payload, id
+		FROM
+			webhook_deliveries
+		WHERE id = $1 AND webhook_id = $2
+		RETURNING ` + deliveryColumns + `
+	`
+	var d model.WebhookDelivery
+	err := r.db.QueryRow(ctx, q, deliveryID, webhookID).Scan(deliveryFields(&d)...)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+const webhookColumns = `
+	id, workspace_id, url, events, click_thresholds, created_at
+`
+
+func scanWebhook(row pgx.Row) (*model.Webhook, error) {
+	var hook model.Webhook
+	err := row.Scan(
+		&hook.ID,
+		&hook.WorkspaceID,
+		&hook.Url,
+		&hook.Events,
+		&hook.ClickThresholds,
+		&hook.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hook, nil
+}
+
+const deliveryColumns = `
+	d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.next_attempt_at,
+	d.response_status, d.error, d.replay_of, d.created_at, d.delivered_at
+`
+
+func deliveryFields(d *model.WebhookDelivery) []any {
+	return []any{
+		&d.ID,
+		&d.WebhookID,
+		&d.Event,
+		&d.Payload,
+		&d.Status,
+		&d.Attempts,
+		&d.NextAttemptAt,
+		&d.ResponseStatus,
+		&d.Error,
+		&d.ReplayOf,
+		&d.CreatedAt,
+		&d.DeliveredAt,
+	}
+}
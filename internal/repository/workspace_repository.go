@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"shorter/internal/model"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type WorkspaceRepository interface {
+	Create(ctx context.Context, ws *model.Workspace, adminKeyID int64) error
+	ListMemberships(ctx context.Context, apiKeyID int64) ([]model.Membership, error)
+	GetMembership(ctx context.Context, workspaceID, apiKeyID int64) (*model.Membership, error)
+	GetDefaultMembership(ctx context.Context, apiKeyID int64) (*model.Membership, error)
+	SetMember(ctx context.Context, workspaceID, apiKeyID int64, role model.Role) error
+	RemoveMember(ctx context.Context, workspaceID, apiKeyID int64) (bool, error)
+}
+
+type PgWorkspaceRepository struct {
+	db *pgxpool.Pool
+}
+
+func NewWorkspaceRepository(db *pgxpool.Pool) *PgWorkspaceRepository {
+	return &PgWorkspaceRepository{db: db}
+}
+
+// Create stores the workspace and makes the given key its admin.
+func (r *PgWorkspaceRepository) Create(ctx context.Context, ws *model.Workspace, adminKeyID int64) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO
+			workspaces (name, created_by)
+		VALUES ($1, $2)
+		RETURNING id, created_at
+	`, ws.Name, adminKeyID).Scan(&ws.ID, &ws.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO
+			workspace_members (workspace_id, api_key_id, role)
+		VALUES ($1, $2, $3)
+	`, ws.ID, adminKeyID, model.RoleAdmin)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *PgWorkspaceRepository) ListMemberships(ctx context.Context, apiKeyID int64) ([]model.Membership, error) {
+	q := `
+		SELECT ` + membershipColumns + `
+		FROM
+			workspace_members m
+			JOIN workspaces w ON w.id = m.workspace_id
+		WHERE m.api_key_id = $1
+		ORDER BY w.id
+	`
+	rows, err := r.db.Query(ctx, q, apiKeyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	memberships := []model.Membership{}
+	for rows.Next() {
+		m, err := scanMembership(rows)
+		if err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, *m)
+	}
+
+	return memberships, rows.Err()
+}
+
+func (r *PgWorkspaceRepository) GetMembership(ctx context.Context, workspaceID, apiKeyID int64) (*model.Membership, error) {
+	q := `
+		SELECT ` + membershipColumns + `
+		FROM
+			workspace_members m
+			JOIN workspaces w ON w.id = m.workspace_id
+		WHERE m.workspace_id = $1 AND m.api_key_id = $2
+	`
+	m, err := scanMembership(r.db.QueryRow(ctx, q, workspaceID, apiKeyID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+
+	return m, err
+}
+
+// GetDefaultMembership returns the oldest workspace of the key, used when
+// a request does not pick a workspace explicitly.
+func (r *PgWorkspaceRepository) GetDefaultMembership(ctx context.Context, apiKeyID int64) (*model.Membership, error) {
+	q := `
+		SELECT ` + membershipColumns + `
+		FROM
+			workspace_members m
+			JOIN workspaces w ON w.id = m.workspace_id
+		WHERE m.api_key_id = $1
+		ORDER BY w.id
+		LIMIT 1
+	`
+	m, err := scanMembership(r.db.QueryRow(ctx, q, apiKeyID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+
+	return m, err
+}
+
+func (r *PgWorkspaceRepository) SetMember(ctx context.Context, workspaceID, apiKeyID int64, role model.Role) error {
+	q := `
+		INSERT INTO
+			workspace_members (workspace_id, api_key_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (workspace_id, api_key_id) DO UPDATE SET role = EXCLUDED.role
+	`
+	_, err := r.db.Exec(ctx, q, workspaceID, apiKeyID, role)
+	return err
+}
+
+func (r *PgWorkspaceRepository) RemoveMember(ctx context.Context, workspaceID, apiKeyID int64) (bool, error) {
+	q := `
+		DELETE FROM workspace_members
+		WHERE workspace_id = $1 AND api_key_id = $2
+	`
+	tag, err := r.db.Exec(ctx, q, workspaceID, apiKeyID)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+const membershipColumns = `
+	m.workspace_id, w.name, m.api_key_id, m.role
+`
+
+func scanMembership(row pgx.Row) (*model.Membership, error) {
+	var m model.Membership
+	err := row.Scan(
+		&m.WorkspaceID,
+		&m.WorkspaceName,
+		&m.APIKeyID,
+		&m.Role,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
@@ -0,0 +1,42 @@
+package request
+
+import (
+	"net/http"
+	"strings"
+)
+
+// crawlerAgents are user agent fragments of bots that unfurl links in
+// social networks and messengers.
+var crawlerAgents = []string{
+	"facebookexternalhit",
+	"facebot",
+	"twitterbot",
+	"linkedinbot",
+	"slackbot",
+	"discordbot",
+	"telegrambot",
+	"whatsapp",
+	"pinterest",
+	"redditbot",
+	"applebot",
+	"skypeuripreview",
+	"vkshare",
+	"embedly",
+	"mastodon",
+}
+
+// IsCrawler reports whether the request comes from a link preview bot.
+func IsCrawler(r *http.Request) bool {
+	ua := strings.ToLower(r.Header.Get("User-Agent"))
+	if ua == "" {
+		return false
+	}
+
+	for _, agent := range crawlerAgents {
+		if strings.Contains(ua, agent) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,145 @@
+package request
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+type clientIPCtxKey struct{}
+
+type trustedProxyCtxKey struct{}
+
+// ParseTrustedProxies parses addresses and CIDR ranges of the proxies allowed
+// to report the client address in X-Forwarded-For.
+func ParseTrustedProxies(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if strings.Contains(value, "/") {
+			prefix, err := netip.ParsePrefix(value)
+			if err != nil {
+				return nil, fmt.Errorf("trusted proxy %q: %w", value, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(value)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", value, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+
+	return prefixes, nil
+}
+
+// TrustProxies resolves the client address of every request. X-Forwarded-For
+// is only read when the connection comes from a trusted proxy, the client is
+// then the rightmost hop that is not a trusted proxy itself, anything left of
+// it may have been forged.
+func TrustProxies(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := WithClientIP(r.Context(), resolveClientIP(r, trusted))
+			if fromTrustedProxy(r, trusted) {
+				ctx = context.WithValue(ctx, trustedProxyCtxKey{}, true)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// FromTrustedProxy reports whether TrustProxies found the connection to come
+// from a trusted proxy, so headers the proxy sets can be believed.
+func FromTrustedProxy(r *http.Request) bool {
+	trusted, _ := r.Context().Value(trustedProxyCtxKey{}).(bool)
+	return trusted
+}
+
+// WithClientIP sets the address ClientIP reports, e.g. for simulated clicks.
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPCtxKey{}, ip)
+}
+
+// ClientIP returns the visitor address resolved by TrustProxies, or the
+// connection address when the middleware is not installed.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPCtxKey{}).(string); ok {
+		return ip
+	}
+
+	return RemoteIP(r)
+}
+
+// RemoteIP returns the address of the connection peer, which unlike
+// forwarding headers cannot be forged by the client.
+func RemoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func fromTrustedProxy(r *http.Request, trusted []netip.Prefix) bool {
+	addr, ok := parseHop(RemoteIP(r))
+	return ok && isTrusted(addr, trusted)
+}
+
+func resolveClientIP(r *http.Request, trusted []netip.Prefix) string {
+	client := RemoteIP(r)
+	if !fromTrustedProxy(r, trusted) {
+		return client
+	}
+
+	// every proxy appends the address it got the request from, walk back
+	// until the first hop we do not operate
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseHop(hops[i])
+		if !ok {
+			break
+		}
+		client = addr.String()
+		if !isTrusted(addr, trusted) {
+			break
+		}
+	}
+
+	return client
+}
+
+// parseHop accepts a bare address or one with a port, as some proxies send.
+func parseHop(hop string) (netip.Addr, bool) {
+	hop = strings.TrimSpace(hop)
+	addr, err := netip.ParseAddr(hop)
+	if err != nil {
+		addrPort, err := netip.ParseAddrPort(hop)
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		addr = addrPort.Addr()
+	}
+
+	return addr.Unmap(), true
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,156 @@
+package routing
+
+import (
+	"shorter/internal/model"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SourceRoutingRules = "routing_rules"
+	SourceDeviceRules  = "device_rules"
+	SourceGeoRules     = "geo_rules"
+	SourceDefault      = "default"
+)
+
+// Decision is the outcome of routing one click. With AppUrl set the app is
+// tried before Url.
+type Decision struct {
+	Url    string `json:"url"`
+	AppUrl string `json:"app_url,omitempty"`
+	Source string `json:"source"`
+	// index and name of the matched rule
+	Index int    `json:"index"`
+	Rule  string `json:"rule,omitempty"`
+}
+
+// Route picks the destination of a click: routing rules first, then device
+// and geo rules, the link's own URL by default.
+func Route(link *model.Link, req *Request) Decision {
+	for i, rule := range link.RoutingRules {
+		if Matches(rule.When, req) {
+			return Decision{Url: rule.Url, Source: SourceRoutingRules, Index: i, Rule: rule.Name}
+		}
+	}
+
+	if i := link.DeviceRule(req.Platform); i >= 0 {
+		rule := link.DeviceRules[i]
+		return Decision{Url: rule.Url, AppUrl: rule.AppUrl, Source: SourceDeviceRules, Index: i}
+	}
+
+	if i := link.GeoRule(req.Country); i >= 0 {
+		return Decision{Url: link.GeoRules[i].Url, Source: SourceGeoRules, Index: i}
+	}
+
+	return Decision{Url: link.OriginalUrl, Source: SourceDefault, Index: -1}
+}
+
+// Matches reports whether the request satisfies every condition.
+func Matches(c model.Conditions, req *Request) bool {
+	if len(c.Countries) > 0 && !containsFold(c.Countries, req.Country) {
+		return false
+	}
+	if len(c.Devices) > 0 && !matchDevice(c.Devices, req.Platform) {
+		return false
+	}
+	if len(c.OS) > 0 && !containsFold(c.OS, req.OS) {
+		return false
+	}
+	if len(c.Browsers) > 0 && !containsFold(c.Browsers, req.Browser) {
+		return false
+	}
+	if len(c.Languages) > 0 && !matchLanguage(c.Languages, req.Languages) {
+		return false
+	}
+	if len(c.RefererDomains) > 0 && !matchDomain(c.RefererDomains, req.RefererDomain) {
+		return false
+	}
+	for key, want := range c.Query {
+		values, ok := req.Query[key]
+		// an empty value only asks for the parameter to be present
+		if !ok || (want != "" && !slices.Contains(values, want)) {
+			return false
+		}
+	}
+
+	if len(c.Weekdays) > 0 || c.Hours != nil {
+		local := req.Time.In(location(c.Timezone))
+		if len(c.Weekdays) > 0 && !slices.Contains(c.Weekdays, weekdays[local.Weekday()]) {
+			return false
+		}
+		if c.Hours != nil && !c.Hours.Contains(local.Hour()) {
+			return false
+		}
+	}
+
+	return true
+}
+
+var weekdays = [...]string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+func containsFold(values []string, s string) bool {
+	if s == "" {
+		return false
+	}
+
+	return slices.ContainsFunc(values, func(v string) bool { return strings.EqualFold(v, s) })
+}
+
+// matchDevice treats "mobile" as any phone or tablet, as device rules do.
+func matchDevice(devices []string, platform string) bool {
+	return slices.Contains(devices, platform) ||
+		(platform != model.PlatformDesktop && slices.Contains(devices, model.PlatformMobile))
+}
+
+// matchLanguage matches "de" against "de" and "de-at", "de-at" only itself.
+func matchLanguage(wanted, languages []string) bool {
+	for _, lang := range languages {
+		for _, w := range wanted {
+			w = strings.ToLower(w)
+			if lang == w || strings.HasPrefix(lang, w+"-") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// matchDomain matches the domain and its subdomains.
+func matchDomain(domains []string, host string) bool {
+	if host == "" {
+		return false
+	}
+
+	for _, d := range domains {
+		d = strings.TrimPrefix(strings.ToLower(d), "www.")
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+
+	return false
+}
+
+var locations sync.Map
+
+// location caches time zones, loading one reads the tz database.
+func location(name string) *time.Location {
+	if name == "" {
+		return time.UTC
+	}
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location)
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		// rejected on create, only possible if the tz database changed
+		loc = time.UTC
+	}
+	locations.Store(name, loc)
+
+	return loc
+}
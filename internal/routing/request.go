@@ -0,0 +1,125 @@
+package routing
+
+import (
+	"net/http"
+	"net/url"
+	"shorter/internal/enricher"
+	"shorter/internal/geo"
+	"shorter/internal/model"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Request is what routing decisions are made on, extracted once per click.
+type Request struct {
+	Country       string     `json:"country,omitempty"`
+	Platform      string     `json:"platform"`
+	OS            string     `json:"os,omitempty"`
+	Browser       string     `json:"browser,omitempty"`
+	Languages     []string   `json:"languages,omitempty"`
+	RefererDomain string     `json:"referer_domain,omitempty"`
+	Query         url.Values `json:"query,omitempty"`
+	Time          time.Time  `json:"time"`
+}
+
+// Router turns HTTP requests into routing requests and picks destinations.
+type Router struct {
+	geo     *geo.Locator
+	devices *enricher.DeviceParser
+}
+
+func NewRouter(geo *geo.Locator, devices *enricher.DeviceParser) *Router {
+	return &Router{
+		geo:     geo,
+		devices: devices,
+	}
+}
+
+// Request extracts the routing data from an incoming click.
+func (rt *Router) Request(r *http.Request, now time.Time) *Request {
+	ua := r.Header.Get("User-Agent")
+	_, os, browser := rt.devices.Parse(ua)
+	platform := rt.devices.Platform(ua)
+
+	return &Request{
+		Country:       rt.geo.Country(r),
+		Platform:      platform,
+		OS:            osFamily(os, platform),
+		Browser:       browser,
+		Languages:     parseAcceptLanguage(r.Header.Get("Accept-Language")),
+		RefererDomain: refererDomain(r.Header.Get("Referer")),
+		Query:         r.URL.Query(),
+		Time:          now,
+	}
+}
+
+// osFamily maps the user agent OS name to the values used in conditions.
+func osFamily(os, platform string) string {
+	switch {
+	case platform == model.PlatformIOS:
+		return "ios"
+	case platform == model.PlatformAndroid:
+		return "android"
+	case strings.Contains(os, "CrOS"):
+		return "chromeos"
+	case strings.Contains(os, "Windows"):
+		return "windows"
+	case strings.Contains(os, "Mac OS X"):
+		return "macos"
+	case strings.Contains(os, "Linux"):
+		return "linux"
+	default:
+		return strings.ToLower(os)
+	}
+}
+
+// parseAcceptLanguage returns language tags, most preferred first, in
+// lower case. Tags with q=0 are dropped.
+func parseAcceptLanguage(header string) []string {
+	type tag struct {
+		name string
+		q    float64
+	}
+
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || name == "*" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			tags = append(tags, tag{name: name, q: q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	languages := make([]string, len(tags))
+	for i, t := range tags {
+		languages[i] = t.name
+	}
+
+	return languages
+}
+
+func refererDomain(referer string) string {
+	if referer == "" {
+		return ""
+	}
+
+	u, err := url.Parse(referer)
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
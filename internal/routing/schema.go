@@ -0,0 +1,83 @@
+package routing
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"shorter/internal/model"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+//go:embed schema.json
+var schemaJSON string
+
+var compiledSchema = sync.OnceValues(func() (*gojsonschema.Schema, error) {
+	return gojsonschema.NewSchema(gojsonschema.NewStringLoader(schemaJSON))
+})
+
+// ValidationError lists every problem found in submitted rules.
+type ValidationError struct {
+	Errors []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid routing rules: " + strings.Join(e.Errors, "; ")
+}
+
+// AsValidationError unwraps a *ValidationError from err.
+func AsValidationError(err error) (*ValidationError, bool) {
+	var verr *ValidationError
+	ok := errors.As(err, &verr)
+
+	return verr, ok
+}
+
+// ParseRules validates raw rules against the JSON schema and decodes them.
+// Problems are returned as *ValidationError.
+func ParseRules(raw json.RawMessage) ([]model.RoutingRule, error) {
+	schema, err := compiledSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(raw))
+	if err != nil {
+		return nil, &ValidationError{Errors: []string{err.Error()}}
+	}
+	if !result.Valid() {
+		verr := &ValidationError{}
+		for _, e := range result.Errors() {
+			verr.Errors = append(verr.Errors, e.String())
+		}
+		return nil, verr
+	}
+
+	var rules []model.RoutingRule
+	if err := json.Unmarshal(raw, &rules); err != nil {
+		return nil, &ValidationError{Errors: []string{err.Error()}}
+	}
+
+	// what the schema can not express
+	verr := &ValidationError{}
+	for i, rule := range rules {
+		if _, err := url.ParseRequestURI(rule.Url); err != nil {
+			verr.Errors = append(verr.Errors, fmt.Sprintf("%d.url: invalid url", i))
+		}
+		if tz := rule.When.Timezone; tz != "" {
+			if _, err := time.LoadLocation(tz); err != nil {
+				verr.Errors = append(verr.Errors, fmt.Sprintf("%d.when.timezone: unknown time zone %q", i, tz))
+			}
+		}
+	}
+	if len(verr.Errors) > 0 {
+		return nil, verr
+	}
+
+	return rules, nil
+}
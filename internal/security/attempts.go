@@ -0,0 +1,80 @@
+package security
+
+import (
+	"sync"
+	"time"
+)
+
+// AttemptLimiter counts attempts per key inside a fixed window and blocks
+// the key once the limit is reached, e.g. to slow down brute force of link
+// passwords.
+type AttemptLimiter struct {
+	mu       sync.Mutex
+	max      int
+	window   time.Duration
+	attempts map[string]*attempt
+}
+
+type attempt struct {
+	count   int
+	resetAt time.Time
+}
+
+func NewAttemptLimiter(max int, window time.Duration) *AttemptLimiter {
+	if max <= 0 {
+		max = 5
+	}
+	if window <= 0 {
+		window = 15 * time.Minute
+	}
+
+	return &AttemptLimiter{
+		max:      max,
+		window:   window,
+		attempts: make(map[string]*attempt),
+	}
+}
+
+// Allow counts an attempt of the key and reports whether it may go ahead
+// and, if not, how long it has to wait. Checking and counting happen under
+// one lock, so a burst of parallel guesses cannot slip past the limit. A
+// successful attempt should Reset the key.
+func (l *AttemptLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	a, ok := l.attempts[key]
+	if !ok || now.After(a.resetAt) {
+		a = &attempt{resetAt: now.Add(l.window)}
+		l.attempts[key] = a
+		l.dropStale(now)
+	}
+
+	if a.count >= l.max {
+		return false, a.resetAt.Sub(now)
+	}
+	a.count++
+
+	return true, 0
+}
+
+// dropStale removes expired keys so the map does not grow forever.
+func (l *AttemptLimiter) dropStale(now time.Time) {
+	if len(l.attempts) <= 10000 {
+		return
+	}
+
+	for k, v := range l.attempts {
+		if now.After(v.resetAt) {
+			delete(l.attempts, k)
+		}
+	}
+}
+
+func (l *AttemptLimiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.attempts, key)
+}
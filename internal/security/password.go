@@ -0,0 +1,20 @@
+package security
+
+import "golang.org/x/crypto/bcrypt"
+
+// MaxPasswordBytes is the bcrypt input limit, longer passwords are refused
+// rather than silently truncated.
+const MaxPasswordBytes = 72
+
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(hash), nil
+}
+
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
@@ -0,0 +1,295 @@
+package urlpolicy
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// SchemeChecker rejects URLs whose scheme is not allowed, e.g. javascript: or file:.
+type SchemeChecker struct {
+	allowed map[string]bool
+}
+
+func NewSchemeChecker(schemes []string) *SchemeChecker {
+	if len(schemes) == 0 {
+		schemes = []string{"http", "https"}
+	}
+
+	allowed := make(map[string]bool, len(schemes))
+	for _, s := range schemes {
+		allowed[strings.ToLower(s)] = true
+	}
+
+	return &SchemeChecker{allowed: allowed}
+}
+
+func (c *SchemeChecker) Check(_ context.Context, u *url.URL) error {
+	if !c.allowed[strings.ToLower(u.Scheme)] {
+		return &Violation{Rule: "scheme", Reason: "scheme " + u.Scheme + ": is not allowed"}
+	}
+	if u.Host == "" {
+		return &Violation{Rule: "scheme", Reason: "url must have a host"}
+	}
+
+	return nil
+}
+
+// DomainListChecker applies allow and deny lists. A listed domain also
+// matches all of its subdomains. With a non-empty allow list only listed
+// domains are accepted.
+type DomainListChecker struct {
+	allow []string
+	deny  []string
+}
+
+func NewDomainListChecker(allow, deny []string) *DomainListChecker {
+	return &DomainListChecker{
+		allow: normalizeDomains(allow),
+		deny:  normalizeDomains(deny),
+	}
+}
+
+func (c *DomainListChecker) Check(_ context.Context, u *url.URL) error {
+	host := hostname(u)
+
+	if matchDomain(host, c.deny) {
+		return &Violation{Rule: "domain_deny", Reason: "domain " + host + " is blocked"}
+	}
+	if len(c.allow) > 0 && !matchDomain(host, c.allow) {
+		return &Violation{Rule: "domain_allow", Reason: "domain " + host + " is not in the allow list"}
+	}
+
+	return nil
+}
+
+// PrivateIPChecker rejects destinations in loopback, private, link-local and
+// other non-public ranges. Hostnames are resolved when resolve is set, so
+// names pointing at internal addresses are caught too.
+type PrivateIPChecker struct {
+	resolve  bool
+	resolver *net.Resolver
+	timeout  time.Duration
+}
+
+func NewPrivateIPChecker(resolve bool) *PrivateIPChecker {
+	return &PrivateIPChecker{
+		resolve:  resolve,
+		resolver: net.DefaultResolver,
+		timeout:  2 * time.Second,
+	}
+}
+
+func (c *PrivateIPChecker) Check(ctx context.Context, u *url.URL) error {
+	host := hostname(u)
+
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return &Violation{Rule: "private_ip", Reason: "loopback destinations are not allowed"}
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return checkIP(addr)
+	}
+
+	if !c.resolve {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	addrs, err := c.resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return &Violation{Rule: "private_ip", Reason: "host " + host + " cannot be resolved"}
+	}
+	for _, ipAddr := range addrs {
+		addr, ok := netip.AddrFromSlice(ipAddr.IP)
+		if !ok {
+			continue
+		}
+		if err := checkIP(addr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// nonPublicPrefixes are the ranges of the IANA IPv4 and IPv6 special-purpose
+// address registries that are not globally reachable, plus multicast and
+// the reserved 240.0.0.0/4.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("192.88.99.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+
+	// unspecified, loopback and the deprecated ipv4-compatible addresses
+	netip.MustParsePrefix("::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001::/23"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"),
+	netip.MustParsePrefix("3fff::/20"),
+	netip.MustParsePrefix("5f00::/16"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("fec0::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+// nat64Prefix embeds an IPv4 address in its last 32 bits.
+var nat64Prefix = netip.MustParsePrefix("64:ff9b::/96")
+
+func checkIP(addr netip.Addr) error {
+	// ::ffff:10.0.0.1 and 64:ff9b::a00:1 reach 10.0.0.1
+	addr = addr.Unmap().WithZone("")
+	if nat64Prefix.Contains(addr) {
+		ip := addr.As16()
+		addr = netip.AddrFrom4([4]byte(ip[12:]))
+	}
+
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return &Violation{Rule: "private_ip", Reason: "private and loopback addresses are not allowed"}
+		}
+	}
+
+	return nil
+}
+
+// SelfHostChecker detects redirect loops: links pointing back at one of our
+// own short domains.
+type SelfHostChecker struct {
+	hosts func() []string
+}
+
+// NewSelfHostChecker takes a function so the list can follow runtime data
+// such as configured domains.
+func NewSelfHostChecker(hosts func() []string) *SelfHostChecker {
+	return &SelfHostChecker{hosts: hosts}
+}
+
+func (c *SelfHostChecker) Check(_ context.Context, u *url.URL) error {
+	if matchHost(hostname(u), normalizeDomains(c.hosts())) {
+		return &Violation{Rule: "redirect_loop", Reason: "links to the shortener itself are not allowed"}
+	}
+
+	return nil
+}
+
+// KnownHostChecker detects redirect loops through hosts that are looked up
+// at check time, e.g. custom short domains stored in the database.
+type KnownHostChecker struct {
+	lookup func(ctx context.Context, host string) (bool, error)
+}
+
+func NewKnownHostChecker(lookup func(ctx context.Context, host string) (bool, error)) *KnownHostChecker {
+	return &KnownHostChecker{lookup: lookup}
+}
+
+func (c *KnownHostChecker) Check(ctx context.Context, u *url.URL) error {
+	known, err := c.lookup(ctx, hostname(u))
+	if err != nil {
+		return err
+	}
+	if known {
+		return &Violation{Rule: "redirect_loop", Reason: "links to the shortener itself are not allowed"}
+	}
+
+	return nil
+}
+
+// LoadDomainList reads one domain per line, skipping blank lines and # comments.
+func LoadDomainList(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var domains []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			domains = append(domains, line)
+		}
+	}
+
+	return domains, scanner.Err()
+}
+
+func normalizeDomains(domains []string) []string {
+	result := make([]string, 0, len(domains))
+	for _, d := range domains {
+		d = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(d)), "*.")
+		d = strings.TrimSuffix(d, ".")
+		if d != "" {
+			result = append(result, d)
+		}
+	}
+
+	return result
+}
+
+func matchDomain(host string, domains []string) bool {
+	for _, d := range domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchHost(host string, hosts []string) bool {
+	for _, h := range hosts {
+		if name, _, err := net.SplitHostPort(h); err == nil {
+			h = name
+		}
+		if host == h {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PublicDialControl is a net.Dialer Control func refusing connections to
+// non-public addresses, for clients that fetch user supplied URLs. It also
+// covers redirects and DNS answers that changed after the link was created.
+func PublicDialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return checkIP(addr)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package urlpolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Violation is returned when a destination URL breaks a policy rule.
+// Reason is safe to show to API clients.
+type Violation struct {
+	Rule   string
+	Reason string
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("%s: %s", v.Rule, v.Reason)
+}
+
+// AsViolation unwraps a policy violation from err.
+func AsViolation(err error) (*Violation, bool) {
+	var v *Violation
+	ok := errors.As(err, &v)
+	return v, ok
+}
+
+// Checker inspects a destination URL. It returns a *Violation when the URL
+// must be rejected and a plain error when the check itself failed, e.g. a
+// threat feed being unreachable.
+type Checker interface {
+	Check(ctx context.Context, u *url.URL) error
+}
+
+// CheckerFunc adapts a function to the Checker interface.
+type CheckerFunc func(ctx context.Context, u *url.URL) error
+
+func (f CheckerFunc) Check(ctx context.Context, u *url.URL) error {
+	return f(ctx, u)
+}
+
+// Engine runs checkers in the order they were added and stops at the first
+// rejection.
+type Engine struct {
+	checkers []Checker
+}
+
+func NewEngine(checkers ...Checker) *Engine {
+	return &Engine{checkers: checkers}
+}
+
+// Use appends a checker, e.g. a threat feed lookup.
+func (e *Engine) Use(c Checker) {
+	e.checkers = append(e.checkers, c)
+}
+
+func (e *Engine) Check(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return &Violation{Rule: "syntax", Reason: "url cannot be parsed"}
+	}
+
+	for _, c := range e.checkers {
+		if err := c.Check(ctx, u); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hostname returns the lowercased host without port and trailing dot.
+func hostname(u *url.URL) string {
+	return strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+}
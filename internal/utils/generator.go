@@ -13,7 +13,7 @@ func GenerateRandomAlias(length int) (string, error) {
 	for i := range length {
 		idx, err := rand.Int(rand.Reader, big.NewInt(int64(len(lettersAndDigits))))
 		if err != nil {
-			return "", nil
+			return "", err
 		}
 		result[i] = lettersAndDigits[idx.Int64()]
 	}
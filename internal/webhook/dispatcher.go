@@ -0,0 +1,249 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand/v2"
+	"shorter/internal/metrics"
+	"shorter/internal/model"
+	"shorter/internal/repository"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMaxAttempts = 8
+	defaultBackoff     = 30 * time.Second
+	defaultMaxBackoff  = 6 * time.Hour
+	defaultConcurrency = 4
+
+	pollInterval   = 2 * time.Second
+	expiryInterval = time.Minute
+	expiryBatch    = 100
+	// deliveries claimed per worker and poll
+	claimPerWorker = 4
+)
+
+// Dispatcher queues events for the webhooks subscribed to them and
+// delivers the queue with retries and exponential backoff.
+type Dispatcher struct {
+	repo        repository.WebhookRepository
+	links       repository.LinkRepository
+	domains     repository.DomainRepository
+	sender      *Sender
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	concurrency int
+	logger      *zap.Logger
+}
+
+func NewDispatcher(
+	repo repository.WebhookRepository,
+	links repository.LinkRepository,
+	domains repository.DomainRepository,
+	sender *Sender,
+	maxAttempts int,
+	backoff time.Duration,
+	maxBackoff time.Duration,
+	concurrency int,
+	logger *zap.Logger,
+) *Dispatcher {
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	return &Dispatcher{
+		repo:        repo,
+		links:       links,
+		domains:     domains,
+		sender:      sender,
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+		maxBackoff:  maxBackoff,
+		concurrency: concurrency,
+		logger:      logger,
+	}
+}
+
+// Emit queues the event for the webhooks of the workspace subscribed to it.
+func (d *Dispatcher) Emit(ctx context.Context, workspaceID int64, event string, data any) error {
+	return d.enqueue(ctx, workspaceID, event, data, nil)
+}
+
+// EmitThreshold queues click.threshold_reached for the webhooks of the
+// workspace watching this click count.
+func (d *Dispatcher) EmitThreshold(ctx context.Context, workspaceID int64, clicks int, data any) error {
+	return d.enqueue(ctx, workspaceID, model.WebhookClickThresholdReached, data, &clicks)
+}
+
+func (d *Dispatcher) enqueue(ctx context.Context, workspaceID int64, event string, data any, clicks *int) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.repo.Enqueue(ctx, workspaceID, event, payload, clicks)
+
+	return err
+}
+
+// Start runs until ctx is cancelled, delivering due events and announcing
+// links that expired.
+func (d *Dispatcher) Start(ctx context.Context) error {
+	d.logger.Info("starting webhook dispatcher")
+
+	poll := time.NewTicker(pollInterval)
+	defer poll.Stop()
+	expiry := time.NewTicker(expiryInterval)
+	defer expiry.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-poll.C:
+			d.deliverDue(ctx)
+		case <-expiry.C:
+			d.announceExpired(ctx)
+		}
+	}
+}
+
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	batch := d.concurrency * claimPerWorker
+	// long enough for every worker to go through its share of the batch
+	lease := time.Duration(claimPerWorker+1)*d.sender.timeout + time.Minute
+
+	for ctx.Err() == nil {
+		deliveries, err := d.repo.ClaimDue(ctx, batch, time.Now().Add(lease))
+		if err != nil {
+			if ctx.Err() == nil {
+				d.logger.Error("failed to claim webhook deliveries", zap.Error(err))
+			}
+			return
+		}
+
+		jobs := make(chan int, len(deliveries))
+		for i := range deliveries {
+			jobs <- i
+		}
+		close(jobs)
+
+		var wg sync.WaitGroup
+		for range min(d.concurrency, len(deliveries)) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range jobs {
+					d.deliver(ctx, &deliveries[i])
+				}
+			}()
+		}
+		wg.Wait()
+
+		// a full batch means more may be waiting
+		if len(deliveries) < batch {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery *model.WebhookDelivery) {
+	status, err := d.sender.Send(ctx, delivery)
+	if err == nil {
+		metrics.WebhookDeliveriesTotal.WithLabelValues(model.DeliveryDelivered).Inc()
+		if err := d.repo.MarkDelivered(ctx, delivery.ID, status); err != nil {
+			d.logger.Error("failed to mark webhook delivered", zap.Error(err), zap.Int64("delivery_id", delivery.ID))
+		}
+		return
+	}
+	if ctx.Err() != nil {
+		// shutting down, the lease expires and another attempt follows
+		return
+	}
+
+	var retryAt *time.Time
+	result := model.DeliveryFailed
+	if delivery.Attempts < d.maxAttempts {
+		at := time.Now().Add(d.delay(delivery.Attempts))
+		retryAt = &at
+		result = "retry"
+	}
+	metrics.WebhookDeliveriesTotal.WithLabelValues(result).Inc()
+
+	var responseStatus *int
+	if status != 0 {
+		responseStatus = &status
+	}
+	if err := d.repo.MarkFailed(ctx, delivery.ID, responseStatus, err.Error(), retryAt); err != nil {
+		d.logger.Error("failed to mark webhook attempt", zap.Error(err), zap.Int64("delivery_id", delivery.ID))
+	}
+
+	if retryAt == nil {
+		d.logger.Warn("webhook delivery failed",
+			zap.Int64("delivery_id", delivery.ID),
+			zap.Int64("webhook_id", delivery.WebhookID),
+			zap.String("event", delivery.Event),
+			zap.Int("attempts", delivery.Attempts),
+			zap.Error(err),
+		)
+	}
+}
+
+// delay doubles the backoff after each attempt, up to maxBackoff, with up
+// to 10% jitter so failed receivers are not hit by all retries at once.
+func (d *Dispatcher) delay(attempts int) time.Duration {
+	delay := d.maxBackoff
+	if attempts < 32 {
+		delay = min(d.backoff<<(attempts-1), d.maxBackoff)
+	}
+
+	return delay + rand.N(delay/10+1)
+}
+
+func (d *Dispatcher) announceExpired(ctx context.Context) {
+	for ctx.Err() == nil {
+		links, err := d.links.ClaimExpired(ctx, expiryBatch)
+		if err != nil {
+			if ctx.Err() == nil {
+				d.logger.Error("failed to load expired links", zap.Error(err))
+			}
+			return
+		}
+
+		now := time.Now()
+		for i := range links {
+			link := &links[i]
+			if link.WorkspaceID == nil {
+				continue
+			}
+
+			var domain *model.Domain
+			if link.DomainID != nil {
+				if domain, err = d.domains.GetByID(ctx, *link.DomainID); err != nil {
+					d.logger.Error("failed to get link domain", zap.Error(err), zap.Int64("link_id", link.ID))
+				}
+			}
+
+			if err := d.Emit(ctx, *link.WorkspaceID, model.WebhookLinkExpired, NewLinkData(link, domain, now)); err != nil {
+				d.logger.Error("failed to queue link.expired", zap.Error(err), zap.Int64("link_id", link.ID))
+			}
+		}
+
+		if len(links) < expiryBatch {
+			return
+		}
+	}
+}
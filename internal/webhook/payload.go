@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"shorter/internal/events"
+	"shorter/internal/model"
+	"time"
+)
+
+// LinkPayload describes a link in link.* events.
+type LinkPayload struct {
+	ID          int64            `json:"id"`
+	Alias       string           `json:"alias"`
+	Domain      string           `json:"domain,omitempty"`
+	OriginalUrl string           `json:"original_url"`
+	Status      model.LinkStatus `json:"status"`
+	ClickCount  int              `json:"click_count"`
+	MaxClicks   *int             `json:"max_clicks,omitempty"`
+	CreatedAt   time.Time        `json:"created_at"`
+	ExpiresAt   *time.Time       `json:"expires_at,omitempty"`
+}
+
+type LinkData struct {
+	Link LinkPayload `json:"link"`
+	// who changed the link and why, for link.updated
+	ChangedBy string `json:"changed_by,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// ClickPayload is a click in click.* events. The visitor IP is left out.
+type ClickPayload struct {
+	LinkID    int64     `json:"link_id"`
+	Alias     string    `json:"alias"`
+	Timestamp time.Time `json:"timestamp"`
+	UserAgent string    `json:"user_agent"`
+	Referer   string    `json:"referer,omitempty"`
+	Variant   string    `json:"variant,omitempty"`
+}
+
+type ClickData struct {
+	Click ClickPayload `json:"click"`
+	// click count reached, for click.threshold_reached
+	Threshold int `json:"threshold,omitempty"`
+}
+
+func NewLinkData(link *model.Link, domain *model.Domain, now time.Time) LinkData {
+	payload := LinkPayload{
+		ID:          link.ID,
+		Alias:       link.Alias,
+		OriginalUrl: link.OriginalUrl,
+		Status:      link.Status(now),
+		ClickCount:  link.ClickCount,
+		MaxClicks:   link.MaxClicks,
+		CreatedAt:   link.CreatedAt,
+		ExpiresAt:   link.ExpiresAt,
+	}
+	if domain != nil {
+		payload.Domain = domain.Hostname
+	}
+
+	return LinkData{Link: payload}
+}
+
+func NewClickData(event *events.ClickEvent) ClickData {
+	return ClickData{
+		Click: ClickPayload{
+			LinkID:    event.LinkID,
+			Alias:     event.Alias,
+			Timestamp: event.Timestamp,
+			UserAgent: event.UserAgent,
+			Referer:   event.Referer,
+			Variant:   event.Variant,
+		},
+	}
+}
@@ -0,0 +1,103 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"shorter/internal/model"
+	"shorter/internal/urlpolicy"
+	"strconv"
+	"time"
+)
+
+const (
+	userAgent = "shorter-webhooks/1.0"
+	// enough of a response body to keep the connection reusable
+	maxBodyRead = 64 << 10
+)
+
+// envelope is the request body of a delivery.
+type envelope struct {
+	ID        int64           `json:"id"`
+	Event     string          `json:"event"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Sender posts signed deliveries to webhook URLs.
+type Sender struct {
+	client  *http.Client
+	timeout time.Duration
+}
+
+// NewSender builds a sender, with publicOnly it refuses to connect to
+// private and loopback addresses. Redirects are not followed.
+func NewSender(timeout time.Duration, publicOnly bool) *Sender {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	if publicOnly {
+		dialer.Control = urlpolicy.PublicDialControl
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	if publicOnly {
+		// through a proxy the dialer would only ever see the proxy address
+		transport.Proxy = nil
+	}
+
+	return &Sender{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		timeout: timeout,
+	}
+}
+
+// Send delivers once and returns the response status, 0 if there was no
+// response. Any status outside 2xx is an error.
+func (s *Sender) Send(ctx context.Context, d *model.WebhookDelivery) (int, error) {
+	body, err := json.Marshal(envelope{
+		ID:        d.ID,
+		Event:     d.Event,
+		CreatedAt: d.CreatedAt,
+		Data:      d.Payload,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Shorter-Event", d.Event)
+	req.Header.Set("Shorter-Delivery", strconv.FormatInt(d.ID, 10))
+	req.Header.Set("Shorter-Signature", Sign(d.Secret, time.Now().Unix(), body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.CopyN(io.Discard, resp.Body, maxBodyRead)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
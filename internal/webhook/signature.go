@@ -0,0 +1,38 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+const (
+	secretPrefix = "whsec_"
+	secretBytes  = 24
+)
+
+// GenerateSecret returns a new random signing secret for a webhook.
+func GenerateSecret() (string, error) {
+	b := make([]byte, secretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return secretPrefix + hex.EncodeToString(b), nil
+}
+
+// Sign returns the signature header value "t=<unix>,v1=<hex>". The HMAC
+// covers the timestamp and the body, so receivers can reject replayed
+// requests by their age.
+func Sign(secret string, timestamp int64, body []byte) string {
+	t := strconv.FormatInt(timestamp, 10)
+
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(t))
+	h.Write([]byte{'.'})
+	h.Write(body)
+
+	return "t=" + t + ",v1=" + hex.EncodeToString(h.Sum(nil))
+}
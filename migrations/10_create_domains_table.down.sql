@@ -0,0 +1,10 @@
+ALTER TABLE link_state_changes DROP COLUMN IF EXISTS link_id;
+
+DROP INDEX IF EXISTS idx_enriched_clicks_link_id;
+ALTER TABLE enriched_clicks DROP COLUMN IF EXISTS link_id;
+
+DROP INDEX IF EXISTS idx_short_links_domain_alias;
+ALTER TABLE short_links DROP COLUMN IF EXISTS domain_id;
+ALTER TABLE short_links ADD CONSTRAINT short_links_alias_key UNIQUE (alias);
+
+DROP TABLE IF EXISTS domains;
\ No newline at end of file
@@ -0,0 +1,21 @@
+CREATE TABLE domains (
+    id BIGSERIAL PRIMARY KEY,
+    hostname VARCHAR(255) UNIQUE NOT NULL,
+    workspace_id BIGINT REFERENCES workspaces(id) ON DELETE CASCADE,
+    fallback_url TEXT,
+    not_found_url TEXT,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+-- aliases are unique per domain, links without domain use the default host
+ALTER TABLE short_links ADD COLUMN IF NOT EXISTS domain_id BIGINT REFERENCES domains(id);
+ALTER TABLE short_links DROP CONSTRAINT IF EXISTS short_links_alias_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_short_links_domain_alias ON short_links (COALESCE(domain_id, 0), alias);
+
+-- clicks and state changes point at the link itself, the alias alone is ambiguous now
+ALTER TABLE enriched_clicks ADD COLUMN IF NOT EXISTS link_id BIGINT;
+UPDATE enriched_clicks ec SET link_id = sl.id FROM short_links sl WHERE sl.alias = ec.alias;
+CREATE INDEX IF NOT EXISTS idx_enriched_clicks_link_id ON enriched_clicks(link_id);
+
+ALTER TABLE link_state_changes ADD COLUMN IF NOT EXISTS link_id BIGINT;
+UPDATE link_state_changes lsc SET link_id = sl.id FROM short_links sl WHERE sl.alias = lsc.alias;
\ No newline at end of file
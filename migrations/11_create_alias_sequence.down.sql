@@ -0,0 +1 @@
+DROP SEQUENCE IF EXISTS short_link_alias_seq;
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_short_links_domain_alias_key;
+ALTER TABLE short_links DROP COLUMN IF EXISTS alias_key;
\ No newline at end of file
@@ -0,0 +1,5 @@
+-- lookup key for aliases, lowercased when aliases are case-insensitive
+ALTER TABLE short_links ADD COLUMN IF NOT EXISTS alias_key VARCHAR(100);
+UPDATE short_links SET alias_key = alias WHERE alias_key IS NULL;
+ALTER TABLE short_links ALTER COLUMN alias_key SET NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_short_links_domain_alias_key ON short_links (COALESCE(domain_id, 0), alias_key);
\ No newline at end of file
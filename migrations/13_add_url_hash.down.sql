@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_short_links_owner_url_hash;
+ALTER TABLE short_links DROP COLUMN IF EXISTS url_hash;
\ No newline at end of file
@@ -0,0 +1,3 @@
+-- sha256 of the normalized destination, links created before stay unindexed
+ALTER TABLE short_links ADD COLUMN IF NOT EXISTS url_hash CHAR(64);
+CREATE INDEX IF NOT EXISTS idx_short_links_owner_url_hash ON short_links (owner_id, url_hash) WHERE url_hash IS NOT NULL;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE short_links ADD COLUMN IF NOT EXISTS geo_rules JSONB;
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE short_links DROP COLUMN IF EXISTS device_rules;
\ No newline at end of file
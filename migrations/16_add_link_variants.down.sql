@@ -0,0 +1,4 @@
+ALTER TABLE enriched_clicks DROP COLUMN IF EXISTS variant;
+
+ALTER TABLE short_links DROP COLUMN IF EXISTS sticky_variants;
+ALTER TABLE short_links DROP COLUMN IF EXISTS variants;
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE short_links ADD COLUMN IF NOT EXISTS variants JSONB;
+ALTER TABLE short_links ADD COLUMN IF NOT EXISTS sticky_variants BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE enriched_clicks ADD COLUMN IF NOT EXISTS variant VARCHAR(32);
\ No newline at end of file
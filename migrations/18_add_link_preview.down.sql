@@ -0,0 +1,2 @@
+ALTER TABLE short_links DROP COLUMN IF EXISTS meta;
+ALTER TABLE short_links DROP COLUMN IF EXISTS preview;
\ No newline at end of file
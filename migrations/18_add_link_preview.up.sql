@@ -0,0 +1,2 @@
+ALTER TABLE short_links ADD COLUMN IF NOT EXISTS preview BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE short_links ADD COLUMN IF NOT EXISTS meta JSONB;
\ No newline at end of file
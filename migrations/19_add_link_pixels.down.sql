@@ -0,0 +1,2 @@
+ALTER TABLE short_links DROP COLUMN IF EXISTS pixel_delay_ms;
+ALTER TABLE short_links DROP COLUMN IF EXISTS pixels;
\ No newline at end of file
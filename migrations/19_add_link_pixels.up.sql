@@ -0,0 +1,2 @@
+ALTER TABLE short_links ADD COLUMN IF NOT EXISTS pixels JSONB;
+ALTER TABLE short_links ADD COLUMN IF NOT EXISTS pixel_delay_ms INT;
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS link_health;
\ No newline at end of file
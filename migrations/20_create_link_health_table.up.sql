@@ -0,0 +1,15 @@
+CREATE TABLE link_health (
+    link_id BIGINT PRIMARY KEY REFERENCES short_links(id) ON DELETE CASCADE,
+    checked_at TIMESTAMPTZ NOT NULL,
+    status_code INT,
+    latency_ms INT NOT NULL DEFAULT 0,
+    final_url TEXT,
+    tls_expires_at TIMESTAMPTZ,
+    error TEXT,
+    consecutive_failures INT NOT NULL DEFAULT 0,
+    broken BOOLEAN NOT NULL DEFAULT FALSE,
+    broken_since TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_health_checked_at ON link_health(checked_at);
+CREATE INDEX IF NOT EXISTS idx_link_health_broken ON link_health(broken) WHERE broken;
\ No newline at end of file
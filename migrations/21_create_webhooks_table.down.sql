@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
+
+DROP INDEX IF EXISTS idx_short_links_expiry_pending;
+ALTER TABLE short_links DROP COLUMN IF EXISTS expiry_notified_at;
\ No newline at end of file
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS export_jobs;
+DROP INDEX IF EXISTS idx_enriched_clicks_link_id_timestamp;
\ No newline at end of file
@@ -0,0 +1,23 @@
+CREATE INDEX IF NOT EXISTS idx_enriched_clicks_link_id_timestamp ON enriched_clicks(link_id, timestamp);
+
+CREATE TABLE export_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    workspace_id BIGINT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    link_id BIGINT NOT NULL REFERENCES short_links(id) ON DELETE CASCADE,
+    alias VARCHAR(100) NOT NULL,
+    format VARCHAR(10) NOT NULL,
+    aggregate VARCHAR(10),
+    from_time TIMESTAMPTZ,
+    to_time TIMESTAMPTZ,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'done', 'failed')),
+    file_path TEXT,
+    row_count BIGINT,
+    error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_export_jobs_pending ON export_jobs(created_at) WHERE status IN ('pending', 'running');
+CREATE INDEX IF NOT EXISTS idx_export_jobs_expires_at ON export_jobs(expires_at);
\ No newline at end of file
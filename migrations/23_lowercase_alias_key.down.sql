@@ -0,0 +1 @@
+UPDATE short_links SET alias_key = alias WHERE alias_key <> alias;
\ No newline at end of file
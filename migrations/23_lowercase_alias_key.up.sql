@@ -0,0 +1,29 @@
+-- alias_key is now always the lowercased alias, aliases.case_insensitive only
+-- changes lookups. Aliases differing only in case cannot all get the same key:
+-- the one already holding it, else a lowercase one, else the oldest does, the
+-- others keep their own spelling and are only reachable by it.
+DO $$
+DECLARE
+    conflict RECORD;
+BEGIN
+    FOR conflict IN
+        SELECT COALESCE(domain_id, 0) AS domain_id, string_agg(alias, ', ' ORDER BY id) AS aliases
+        FROM short_links
+        GROUP BY COALESCE(domain_id, 0), lower(alias)
+        HAVING count(*) > 1
+    LOOP
+        RAISE NOTICE 'aliases % (domain %) differ only in case, only one of them opens case-insensitively', conflict.aliases, conflict.domain_id;
+    END LOOP;
+END $$;
+
+WITH ranked AS (
+    SELECT id, row_number() OVER (
+        PARTITION BY COALESCE(domain_id, 0), lower(alias)
+        ORDER BY alias_key = lower(alias) DESC, alias = lower(alias) DESC, id
+    ) AS rank
+    FROM short_links
+)
+UPDATE short_links sl
+SET alias_key = lower(sl.alias)
+FROM ranked
+WHERE sl.id = ranked.id AND ranked.rank = 1 AND sl.alias_key <> lower(sl.alias);
\ No newline at end of file
@@ -0,0 +1 @@
+ALTER TABLE short_links DROP COLUMN IF EXISTS max_clicks;
\ No newline at end of file
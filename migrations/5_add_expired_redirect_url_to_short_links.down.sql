@@ -0,0 +1 @@
+ALTER TABLE short_links DROP COLUMN IF EXISTS expired_redirect_url;
\ No newline at end of file
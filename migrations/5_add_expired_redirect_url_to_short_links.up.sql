@@ -0,0 +1 @@
+ALTER TABLE short_links ADD COLUMN IF NOT EXISTS expired_redirect_url TEXT;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE short_links
+    DROP COLUMN IF EXISTS active_from,
+    DROP COLUMN IF EXISTS scheduled_redirect_url;
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE short_links
+    ADD COLUMN IF NOT EXISTS active_from TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS scheduled_redirect_url TEXT;
\ No newline at end of file
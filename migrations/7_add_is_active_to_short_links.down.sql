@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS link_state_changes;
+
+ALTER TABLE short_links DROP COLUMN IF EXISTS is_active;
\ No newline at end of file
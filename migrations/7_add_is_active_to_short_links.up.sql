@@ -0,0 +1,12 @@
+ALTER TABLE short_links ADD COLUMN IF NOT EXISTS is_active BOOLEAN NOT NULL DEFAULT TRUE;
+
+CREATE TABLE link_state_changes (
+    id BIGSERIAL PRIMARY KEY,
+    alias VARCHAR(100) NOT NULL,
+    is_active BOOLEAN NOT NULL,
+    changed_by VARCHAR(255) NOT NULL,
+    reason TEXT NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_link_state_changes_alias ON link_state_changes(alias);
\ No newline at end of file
@@ -0,0 +1,3 @@
+ALTER TABLE short_links DROP COLUMN IF EXISTS owner_id;
+
+DROP TABLE IF EXISTS api_keys;
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    key_prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) UNIQUE NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ
+);
+
+ALTER TABLE short_links ADD COLUMN IF NOT EXISTS owner_id BIGINT REFERENCES api_keys(id);
+
+CREATE INDEX IF NOT EXISTS idx_short_links_owner_id ON short_links(owner_id);
\ No newline at end of file
@@ -0,0 +1,34 @@
+CREATE TABLE workspaces (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    created_by BIGINT REFERENCES api_keys(id),
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE TABLE workspace_members (
+    workspace_id BIGINT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    api_key_id BIGINT NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('admin', 'editor', 'viewer')),
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    PRIMARY KEY (workspace_id, api_key_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_members_api_key_id ON workspace_members(api_key_id);
+
+ALTER TABLE short_links ADD COLUMN IF NOT EXISTS workspace_id BIGINT REFERENCES workspaces(id);
+
+CREATE INDEX IF NOT EXISTS idx_short_links_workspace_id ON short_links(workspace_id);
+
+-- every existing key gets a personal workspace with the links it owns
+WITH created AS (
+    INSERT INTO workspaces (name, created_by)
+    SELECT name, id FROM api_keys
+    RETURNING id, created_by
+)
+INSERT INTO workspace_members (workspace_id, api_key_id, role)
+SELECT id, created_by, 'admin' FROM created;
+
+UPDATE short_links sl
+SET workspace_id = w.id
+FROM workspaces w
+WHERE w.created_by = sl.owner_id;
\ No newline at end of file